@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/grumpzsux/goParams/internal/cache"
+	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/utils"
+)
+
+var (
+	pruneOlderThan time.Duration // Age beyond which cached responses are pruned.
+	pruneAll       bool          // Prune every cached response.
+)
+
+// newCacheCmd returns the "cache" command group for managing the response cache.
+func newCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the response cache",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "stats",
+		Short: "Show the size and age of the response cache",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cacheStats()
+		},
+	})
+	prune := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove expired responses from the cache",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cachePrune()
+		},
+	}
+	prune.Flags().DurationVar(&pruneOlderThan, "older-than", 0, "Remove responses stored longer ago than this (default: the longest of cache_ttl and cache_ttls, 24h unless configured)")
+	prune.Flags().BoolVar(&pruneAll, "all", false, "Remove every cached response")
+	cmd.AddCommand(prune)
+	return cmd
+}
+
+// openCache opens the response cache configured in cfg.
+func openCache(cfg *config.Config) (*cache.Cache, error) {
+	dir := cfg.CacheDir
+	if dir == "" {
+		var err error
+		if dir, err = cache.DefaultDir(); err != nil {
+			return nil, err
+		}
+	}
+	return cache.Open(dir)
+}
+
+// loadCache loads the configuration and opens its response cache, exiting on error.
+func loadCache() (*config.Config, *cache.Cache) {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		logrus.Fatalf("Failed to load configuration: %v", err)
+	}
+	c, err := openCache(cfg)
+	if err != nil {
+		logrus.Fatalf("Failed to open cache: %v", err)
+	}
+	return cfg, c
+}
+
+func cacheStats() {
+	_, c := loadCache()
+	st, err := c.Stats()
+	if err != nil {
+		logrus.Fatalf("Failed to read cache: %v", err)
+	}
+	fmt.Printf("Directory: %s\n", c.Dir())
+	fmt.Printf("Entries:   %d\n", st.Entries)
+	fmt.Printf("Size:      %s\n", utils.HumanReadableSize(uint64(st.Bytes)))
+	if st.Entries > 0 {
+		fmt.Printf("Oldest:    %s\n", st.Oldest.Local().Format(time.RFC3339))
+		fmt.Printf("Newest:    %s\n", st.Newest.Local().Format(time.RFC3339))
+	}
+}
+
+func cachePrune() {
+	cfg, c := loadCache()
+	maxAge := pruneOlderThan
+	if pruneAll {
+		maxAge = 0
+	} else if maxAge <= 0 {
+		// Keep whatever some source may still reuse.
+		maxAge = cfg.MaxCacheTTL()
+	}
+	removed, freed, err := c.Prune(maxAge)
+	if err != nil {
+		logrus.Fatalf("Failed to prune cache: %v", err)
+	}
+	fmt.Printf("Removed %d entries (%s) from %s\n", removed, utils.HumanReadableSize(uint64(freed)), c.Dir())
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/grumpzsux/goParams/internal/config"
+)
+
+// newConfigCmd returns the "config" command group for inspecting the configuration.
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the goParams configuration",
+	}
+	show := &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration with secrets redacted",
+		Long:  "show prints the configuration after merging the user file, the project file, GOPARAMS_* environment variables and command-line flags, with API keys redacted. It accepts the flags of a harvesting run, so adding a run's flags shows the configuration that run would use.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			showConfig()
+		},
+	}
+	addHarvestFlags(show)
+	cmd.AddCommand(show)
+	return cmd
+}
+
+// showConfig prints the effective configuration as YAML, preceded by the files it was loaded from.
+func showConfig() {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		logrus.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := config.Validate(cfg); err != nil {
+		logrus.Fatalf("Invalid configuration: %v", err)
+	}
+	applyFlagOverrides(cfg)
+
+	data, err := yaml.Marshal(cfg.Redacted())
+	if err != nil {
+		logrus.Fatalf("Failed to encode configuration: %v", err)
+	}
+	if len(cfg.Files) == 0 {
+		fmt.Println("# No configuration files found; using defaults and environment variables.")
+	}
+	for _, f := range cfg.Files {
+		fmt.Printf("# Loaded from %s\n", f)
+	}
+	os.Stdout.Write(data)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/grumpzsux/goParams/internal/utils"
+)
+
+// newDiffCmd returns the "diff" subcommand, which compares two goParams output files.
+func newDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff old.json new.json",
+		Short: "Report URLs and parameters added or removed between two outputs",
+		Long:  "diff compares two goParams outputs (JSON, legacy JSON or plain) and lists the URLs and parameter names that were added or removed.",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			runDiff(args[0], args[1])
+		},
+	}
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file for the diff (if not provided, it is printed to stdout)")
+	return cmd
+}
+
+func runDiff(oldFile, newFile string) {
+	oldURLs, err := utils.LoadURLSet(oldFile)
+	if err != nil {
+		logrus.Fatalf("Failed to read %s: %v", oldFile, err)
+	}
+	newURLs, err := utils.LoadURLSet(newFile)
+	if err != nil {
+		logrus.Fatalf("Failed to read %s: %v", newFile, err)
+	}
+	d := utils.DiffURLs(oldURLs, newURLs)
+
+	out, closeOut := openOutput()
+	defer closeOut()
+	if err := utils.WriteDiff(out, d, outputFormat); err != nil {
+		logrus.Fatalf("Failed to write diff: %v", err)
+	}
+	logrus.Infof("%d URLs added, %d removed; %d parameters added, %d removed", len(d.AddedURLs), len(d.RemovedURLs), len(d.AddedParams), len(d.RemovedParams))
+}
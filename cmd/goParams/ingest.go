@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// newIngestCmd returns the "ingest" subcommand, which harvests URLs from local archive files with
+// the local source instead of querying remote providers.
+func newIngestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ingest [file...]",
+		Short: "Harvest parameterized URLs from local WARC, WAT, CDX and CDXJ files",
+		Long:  "ingest reads local .warc, .wat, .cdx and .cdxj files (optionally gzipped) without making network requests, and cleans and writes the URLs captured on the target domains like a normal run. Files default to local_files from the configuration.",
+		Run: func(cmd *cobra.Command, args []string) {
+			printBanner()
+			runIngest(args)
+		},
+	}
+	addTargetFlags(cmd)
+	return cmd
+}
+
+func runIngest(files []string) {
+	// Only the local source runs, and nothing is fetched, so the response cache is not needed.
+	sources, excludeSources, noCache = []string{"local"}, nil, true
+	cfg, domains := prepareHarvest()
+	// import_files would otherwise add the import source to the selection.
+	cfg.Sources, cfg.ExcludeSources, cfg.ImportFiles = []string{"local"}, nil, nil
+	if len(files) > 0 {
+		cfg.LocalFiles = files
+	}
+	if len(cfg.LocalFiles) == 0 {
+		logrus.Fatal("No files to ingest. Pass archive files as arguments or set local_files in the configuration.")
+	}
+
+	ctx, cancel := harvestContext()
+	defer cancel()
+	summary := harvestToOutput(ctx, cfg, domains, pipelineOptions(cfg))
+	summary.Log()
+}
@@ -3,28 +3,53 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
-	"sync"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"github.com/grumpzsux/goParams/internal/api"
+	"github.com/grumpzsux/goParams/internal/checkpoint"
 	"github.com/grumpzsux/goParams/internal/config"
 	"github.com/grumpzsux/goParams/internal/logger"
+	"github.com/grumpzsux/goParams/internal/pipeline"
+	"github.com/grumpzsux/goParams/internal/psl"
+	"github.com/grumpzsux/goParams/internal/scope"
+	"github.com/grumpzsux/goParams/internal/state"
 	"github.com/grumpzsux/goParams/internal/utils"
 )
 
 var (
-	cfgFile      string
-	verbose      bool
-	concurrency  int
-	outputFormat string
-	domain       string
-	domainList   string
-	placeholder  string // Canary placeholder for cleaning URLs.
-	outputFile   string // New flag for output file.
+	cfgFile        string
+	verbose        bool
+	concurrency    int
+	outputFormat   string
+	domain         string
+	domainList     string
+	placeholder    string        // Canary placeholder for cleaning URLs.
+	outputFile     string        // New flag for output file.
+	sources        []string      // Sources to query (overrides config).
+	excludeSources []string      // Sources to skip (overrides config).
+	ccIndexes      string        // Common Crawl index selection (overrides config).
+	smartDedupe    bool          // Collapse URLs that share a pattern.
+	scopeFile      string        // Scope file replacing the configured scope rules.
+	subdomains     bool          // Harvest every subdomain of each target.
+	proxies        []string      // Proxies replacing the configured ones.
+	proxyList      string        // File of proxies replacing the configured list.
+	caBundle       string        // Extra CA certificates to trust.
+	insecure       bool          // Skip TLS certificate verification.
+	timeout        time.Duration // Request timeout replacing the configured timeouts.
+	resume         bool          // Resume an interrupted run from its checkpoint.
+	checkpointFile string        // Checkpoint journal location (default derived from the run).
+	noCache        bool          // Neither read nor write the response cache.
+	refreshCache   bool          // Ignore cached responses but store fresh ones.
+	newOnly        bool          // Output only URLs not seen by earlier runs.
+	importFiles    []string      // Proxy history exports to merge with the harvested URLs.
 )
 
 func main() {
@@ -39,16 +64,21 @@ func main() {
 	}
 
 	// Persistent flags.
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Path to configuration file (default is config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Path to the project configuration file (default is config.yaml if present)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
-	rootCmd.PersistentFlags().IntVarP(&concurrency, "concurrency", "c", 5, "Number of concurrent API requests")
+	rootCmd.PersistentFlags().IntVarP(&concurrency, "concurrency", "c", 0, "Number of concurrent API requests (default from config, or 5)")
 	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output-format", "f", "plain", "Output format: plain or json")
 
 	// Command-specific flags.
-	rootCmd.Flags().StringVarP(&domain, "domain", "d", "", "Target domain (e.g., example.com)")
-	rootCmd.Flags().StringVarP(&domainList, "list", "l", "", "File containing a list of domains/subdomains")
-	rootCmd.Flags().StringVar(&placeholder, "canary", "PLACEHOLDER", "Custom placeholder for URL query parameters when cleaning URLs")
-	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file for results (if not provided, results are printed to stdout)")
+	addHarvestFlags(rootCmd)
+
+	rootCmd.AddCommand(newSourcesCmd())
+	rootCmd.AddCommand(newDoctorCmd())
+	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newCacheCmd())
+	rootCmd.AddCommand(newDiffCmd())
+	rootCmd.AddCommand(newParamsCmd())
+	rootCmd.AddCommand(newIngestCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -56,7 +86,40 @@ func main() {
 	}
 }
 
-func runApp(args []string) {
+// addHarvestFlags registers the flags shared by every command that harvests URLs from the sources.
+func addHarvestFlags(cmd *cobra.Command) {
+	addTargetFlags(cmd)
+	cmd.Flags().StringSliceVar(&sources, "sources", nil, "Comma-separated list of sources to query (default: all, see 'goParams sources')")
+	cmd.Flags().StringSliceVar(&excludeSources, "exclude-sources", nil, "Comma-separated list of sources to skip")
+	cmd.Flags().StringSliceVar(&proxies, "proxy", nil, "HTTP(S) or SOCKS5 proxy URL; repeat or comma-separate to rotate across several (replaces the configured proxies)")
+	cmd.Flags().StringVar(&proxyList, "proxy-list", "", "File with one proxy URL per line to rotate across (replaces the configured proxies)")
+	cmd.Flags().StringVar(&caBundle, "ca-bundle", "", "PEM file with extra CA certificates to trust, e.g. an intercepting proxy's CA")
+	cmd.Flags().BoolVar(&insecure, "insecure", false, "Skip TLS certificate verification")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "Timeout of a single request attempt for every source, e.g. 30s (default 15s, 2m for wayback and memento)")
+	cmd.Flags().BoolVar(&resume, "resume", false, "Resume an interrupted run: skip source pages completed by the previous run of the same command and reuse their URLs")
+	cmd.Flags().StringVar(&checkpointFile, "checkpoint", "", "Checkpoint journal file (default: derived from the targets and sources, under the user cache directory)")
+	cmd.Flags().StringSliceVar(&importFiles, "import", nil, "Burp Suite XML, HAR or ZAP message export to merge with the harvested URLs; repeat or comma-separate for several (enables the import source)")
+	cmd.Flags().BoolVar(&noCache, "no-cache", false, "Do not use the response cache")
+	cmd.Flags().BoolVar(&refreshCache, "refresh", false, "Ignore cached responses and refetch them, updating the cache")
+	cmd.Flags().StringVar(&ccIndexes, "cc-indexes", "", "Common Crawl indexes to query: N newest (default 3), 'all', a date range YYYY-MM-DD..YYYY-MM-DD, or comma-separated IDs")
+}
+
+// addTargetFlags registers the flags that select the targets and shape the output, shared by the
+// harvesting commands and by ingest.
+func addTargetFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&domain, "domain", "d", "", "Target domain (e.g., example.com)")
+	cmd.Flags().StringVarP(&domainList, "list", "l", "", "File containing a list of domains/subdomains")
+	cmd.Flags().StringVar(&placeholder, "canary", "PLACEHOLDER", "Custom placeholder for URL query parameters when cleaning URLs")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file for results (if not provided, results are printed to stdout)")
+	cmd.Flags().BoolVar(&smartDedupe, "smart-dedupe", false, "Collapse URLs that differ only in parameter values or ID-like path segments (numbers, UUIDs, hashes, dates, slugs)")
+	cmd.Flags().BoolVar(&subdomains, "include-subdomains", false, "Harvest URLs for every subdomain of each target (*.domain)")
+	cmd.Flags().StringVar(&scopeFile, "scope", "", "YAML scope file with include/exclude hosts, paths and regexes (replaces the config's scope rules)")
+	cmd.Flags().BoolVar(&newOnly, "new-only", false, "Output only URLs not seen by earlier runs against the same target, and remember the URLs found")
+}
+
+// prepareHarvest initializes logging, loads the configuration with CLI overrides applied and
+// collects the target domains. It exits on any error.
+func prepareHarvest() (*config.Config, []string) {
 	// Initialize logger with the chosen verbosity level.
 	logger.Init(verbose)
 	logrus.Info("Starting goParams...")
@@ -69,8 +132,29 @@ func runApp(args []string) {
 	if err := config.Validate(cfg); err != nil {
 		logrus.Fatalf("Invalid configuration: %v", err)
 	}
-	// Override concurrency if provided from CLI.
-	cfg.Concurrency = concurrency
+	applyFlagOverrides(cfg)
+	if err := api.ConfigureHTTP(cfg); err != nil {
+		logrus.Fatalf("Invalid HTTP configuration: %v", err)
+	}
+	if !cfg.NoCache {
+		if c, err := openCache(cfg); err != nil {
+			logrus.Warnf("Response cache disabled: %v", err)
+		} else {
+			api.UseCache(c, refreshCache)
+		}
+	}
+	_, disabled, err := api.EnabledSources(cfg)
+	for _, d := range disabled {
+		logrus.Warnf("Source %s disabled: missing %s (run 'goParams doctor' for details)", d.Source.Name(), strings.Join(d.Missing, ", "))
+	}
+	if err != nil {
+		logrus.Fatalf("Invalid source selection: %v", err)
+	}
+	if cfg.PublicSuffixList != "" {
+		if err := psl.UseFile(cfg.PublicSuffixList); err != nil {
+			logrus.Fatalf("Failed to load public suffix list: %v", err)
+		}
+	}
 
 	// Collect target domains.
 	var domains []string
@@ -87,55 +171,210 @@ func runApp(args []string) {
 	if len(domains) == 0 {
 		logrus.Fatal("No domains provided. Use -d or -l flag to supply target domains.")
 	}
+	// Normalize targets (case, trailing dots, schemes, IDN) so every source sees the same form.
+	for i, d := range domains {
+		normalized, err := psl.Normalize(d)
+		if err != nil {
+			logrus.Fatalf("Invalid domain %q: %v", d, err)
+		}
+		domains[i] = normalized
+	}
+	return cfg, domains
+}
 
-	// Create a cancellable context with a timeout.
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
+// applyFlagOverrides applies the command-line flags that override configuration settings to cfg.
+func applyFlagOverrides(cfg *config.Config) {
+	// Override concurrency if provided from CLI.
+	if concurrency > 0 {
+		cfg.Concurrency = concurrency
+	}
+	// Override source selection if provided from CLI.
+	if len(sources) > 0 {
+		cfg.Sources = sources
+	}
+	if len(excludeSources) > 0 {
+		cfg.ExcludeSources = excludeSources
+	}
+	if len(importFiles) > 0 {
+		cfg.ImportFiles = importFiles
+	}
+	if len(cfg.ImportFiles) > 0 {
+		cfg.Sources = withSource(cfg.Sources, "import")
+	}
+	if ccIndexes != "" {
+		cfg.CCIndexes = ccIndexes
+	}
+	if subdomains {
+		cfg.IncludeSubdomains = true
+	}
+	if len(proxies) > 0 || proxyList != "" {
+		cfg.Proxy, cfg.Proxies, cfg.ProxyList = "", proxies, proxyList
+	}
+	if caBundle != "" {
+		cfg.CABundle = caBundle
+	}
+	if insecure {
+		cfg.InsecureSkipVerify = true
+	}
+	if timeout > 0 {
+		// The flag applies to every source, overriding per-source timeouts too.
+		cfg.Timeout, cfg.Timeouts = timeout, nil
+	}
+	if noCache {
+		cfg.NoCache = true
+	}
+}
 
-	// Create a semaphore channel for dynamic concurrency.
-	sem := make(chan struct{}, cfg.Concurrency)
-	var wg sync.WaitGroup
-	results := make(map[string][]string)
-	var mu sync.Mutex
-
-	// Process each domain concurrently.
-	for _, d := range domains {
-		wg.Add(1)
-		sem <- struct{}{}
-		go func(target string) {
-			defer wg.Done()
-			logrus.Infof("Processing domain: %s", target)
-			// Query all available data sources concurrently.
-			urls, err := api.FetchAll(ctx, target, cfg)
-			if err != nil {
-				logrus.Errorf("Error fetching URLs for %s: %v", target, err)
+// withSource adds an opt-in source to a source selection. An empty selection stands for the default
+// sources, which are listed explicitly so that the opt-in source runs alongside them.
+func withSource(selection []string, name string) []string {
+	if len(selection) == 0 {
+		for _, s := range api.Sources() {
+			if !api.IsOptIn(s) {
+				selection = append(selection, s.Name())
 			}
-			// Clean the URLs using the user-defined placeholder.
-			cleanedURLs := utils.CleanURLs(urls, utils.HardcodedExtensions, placeholder)
-			mu.Lock()
-			results[target] = cleanedURLs
-			mu.Unlock()
-			<-sem
-		}(d)
-	}
-	wg.Wait()
-
-	// Output results.
-	if outputFile != "" {
-		// Write results to file.
-		if err := utils.WriteResultsToFile(outputFile, results, outputFormat); err != nil {
-			logrus.Errorf("Failed to write output file: %v", err)
-		} else {
-			logrus.Infof("Output written to %s", outputFile)
 		}
-	} else {
-		// Print results to stdout.
-		if outputFormat == "json" {
-			utils.OutputJSON(results)
-		} else {
-			utils.OutputPlain(results)
+	}
+	for _, existing := range selection {
+		if strings.EqualFold(strings.TrimSpace(existing), name) {
+			return selection
+		}
+	}
+	return append(selection, name)
+}
+
+// pipelineOptions returns the processing options selected on the command line and in cfg.
+func pipelineOptions(cfg *config.Config) pipeline.Options {
+	rules := cfg.Scope
+	if scopeFile != "" {
+		var err error
+		if rules, err = scope.LoadFile(scopeFile); err != nil {
+			logrus.Fatalf("Failed to load scope file: %v", err)
 		}
 	}
+	sc, err := scope.New(rules, cfg.IncludeSubdomains)
+	if err != nil {
+		logrus.Fatalf("Invalid scope: %v", err)
+	}
+	return pipeline.Options{
+		Extensions:  utils.HardcodedExtensions,
+		Placeholder: placeholder,
+		SmartDedupe: smartDedupe,
+		Scope:       sc,
+	}
+}
+
+// newOnlyWriter wraps w to drop URLs seen by earlier runs if --new-only was given.
+func newOnlyWriter(cfg *config.Config, w utils.ResultWriter) utils.ResultWriter {
+	if !newOnly {
+		return w
+	}
+	dir := cfg.StateDir
+	if dir == "" {
+		var err error
+		if dir, err = state.DefaultDir(); err != nil {
+			logrus.Fatalf("Failed to locate state directory: %v", err)
+		}
+	}
+	store, err := state.Open(dir)
+	if err != nil {
+		logrus.Fatalf("Failed to open state directory: %v", err)
+	}
+	return state.NewOnly(w, store)
+}
+
+// harvestContext returns a context that is cancelled on interrupt or termination, so that an
+// interrupted run stops cleanly and can be resumed from its checkpoint.
+func harvestContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// openCheckpoint opens the checkpoint journal for a run over domains. Without --checkpoint, the
+// journal location is derived from the targets and source selection, so the same command finds it again.
+func openCheckpoint(cfg *config.Config, domains []string) *checkpoint.Journal {
+	path := checkpointFile
+	if path == "" {
+		enabled, _, _ := api.EnabledSources(cfg)
+		parts := append([]string(nil), domains...)
+		for _, s := range enabled {
+			parts = append(parts, "source="+s.Name())
+		}
+		parts = append(parts, fmt.Sprintf("subdomains=%t", cfg.IncludeSubdomains), "cc="+cfg.CCIndexes)
+		var err error
+		if path, err = checkpoint.DefaultPath(parts...); err != nil {
+			logrus.Fatalf("Failed to locate checkpoint: %v", err)
+		}
+	}
+	journal, err := checkpoint.Open(path, resume)
+	if err != nil {
+		logrus.Fatalf("Failed to open checkpoint: %v", err)
+	}
+	if resume {
+		logrus.Infof("Resuming from %s: %d completed pages", path, journal.Loaded())
+	}
+	return journal
+}
+
+// finishCheckpoint deletes the journal after a complete run. If the run was interrupted or a source
+// failed, the journal is kept so that the run can be resumed.
+func finishCheckpoint(ctx context.Context, journal *checkpoint.Journal, summary *api.Summary) {
+	if ctx.Err() == nil && summary.ErrorCount() == 0 {
+		if err := journal.Remove(); err != nil {
+			logrus.Warnf("Failed to remove checkpoint: %v", err)
+		}
+		return
+	}
+	if err := journal.Close(); err != nil {
+		logrus.Warnf("Failed to close checkpoint: %v", err)
+	}
+	logrus.Warnf("Run incomplete; rerun the same command with --resume to continue (checkpoint: %s)", journal.Path())
+}
+
+// openOutput returns the output file, or stdout if no file was requested, and a function to close it.
+func openOutput() (io.Writer, func()) {
+	if outputFile == "" {
+		return os.Stdout, func() {}
+	}
+	f, err := os.Create(outputFile)
+	if err != nil {
+		logrus.Fatalf("Failed to create output file: %v", err)
+	}
+	return f, func() { f.Close() }
+}
+
+func runApp(args []string) {
+	cfg, domains := prepareHarvest()
+
+	// Runs are no longer bounded by a fixed timeout; an interrupted run can be resumed.
+	ctx, cancel := harvestContext()
+	defer cancel()
+	journal := openCheckpoint(cfg, domains)
+	opts := pipelineOptions(cfg)
+	opts.Checkpoint = journal
+
+	summary := harvestToOutput(ctx, cfg, domains, opts)
+	finishCheckpoint(ctx, journal, summary)
+	summary.Log()
+}
+
+// harvestToOutput runs the pipeline over domains, streaming results to stdout or the output file
+// as they arrive, and returns the run summary.
+func harvestToOutput(ctx context.Context, cfg *config.Config, domains []string, opts pipeline.Options) *api.Summary {
+	out, closeOut := openOutput()
+	defer closeOut()
+	writer, err := utils.NewResultWriter(out, outputFormat, utils.OutputOptions{GroupByHost: cfg.IncludeSubdomains, Absorbed: smartDedupe})
+	if err != nil {
+		logrus.Fatalf("Invalid output format: %v", err)
+	}
+	writer = newOnlyWriter(cfg, writer)
+
+	summary := pipeline.Run(ctx, domains, cfg, opts, writer)
+	if err := writer.Close(); err != nil {
+		logrus.Errorf("Failed to write output: %v", err)
+	} else if outputFile != "" {
+		logrus.Infof("Output written to %s", outputFile)
+	}
+	return summary
 }
 
 // printBanner displays an ASCII banner at startup.
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/grumpzsux/goParams/internal/pipeline"
+	"github.com/grumpzsux/goParams/internal/utils"
+)
+
+var (
+	paramsPerDomain bool // Group parameter names by domain instead of globally.
+	paramsCounts    bool // Include each name's occurrence count.
+	paramsExamples  int  // Number of example endpoints to list per name.
+)
+
+// newParamsCmd returns the "params" subcommand, which harvests URLs like the root command but
+// outputs a frequency-sorted wordlist of unique query parameter names.
+func newParamsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "params",
+		Short: "Output a frequency-sorted wordlist of unique parameter names",
+		Long:  "params harvests URLs from the selected sources and prints the unique query parameter names found, most frequent first, ready for fuzzing tools such as Arjun or ffuf.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printBanner()
+			runParams()
+		},
+	}
+	addHarvestFlags(cmd)
+	cmd.Flags().BoolVar(&paramsPerDomain, "per-domain", false, "List parameter names separately for each domain")
+	cmd.Flags().BoolVar(&paramsCounts, "counts", false, "Include the number of unique URLs each parameter appears in")
+	cmd.Flags().IntVar(&paramsExamples, "examples", 0, "Number of example endpoints to list for each parameter")
+	return cmd
+}
+
+func runParams() {
+	cfg, domains := prepareHarvest()
+
+	ctx, cancel := harvestContext()
+	defer cancel()
+	journal := openCheckpoint(cfg, domains)
+	opts := pipelineOptions(cfg)
+	opts.Checkpoint = journal
+
+	collector := utils.NewParamCollector(paramsPerDomain, paramsExamples)
+	summary := pipeline.Run(ctx, domains, cfg, opts, newOnlyWriter(cfg, collector))
+	finishCheckpoint(ctx, journal, summary)
+
+	out, closeOut := openOutput()
+	defer closeOut()
+	if err := utils.WriteParams(out, collector.Stats(), outputFormat, paramsCounts); err != nil {
+		logrus.Errorf("Failed to write output: %v", err)
+	} else if outputFile != "" {
+		logrus.Infof("Output written to %s", outputFile)
+	}
+	summary.Log()
+}
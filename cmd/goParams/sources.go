@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grumpzsux/goParams/internal/api"
+	"github.com/grumpzsux/goParams/internal/config"
+)
+
+// newSourcesCmd returns the "sources" subcommand, which lists the registered data sources.
+func newSourcesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "sources",
+		Short: "List the available data sources",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			listSources()
+		},
+	}
+}
+
+// newDoctorCmd returns the "doctor" subcommand, which reports which sources can run with the
+// current configuration.
+func newDoctorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the configuration and report which sources are usable",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if !runDoctor() {
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringSliceVar(&sources, "sources", nil, "Comma-separated list of sources to check (default: all)")
+	cmd.Flags().StringSliceVar(&excludeSources, "exclude-sources", nil, "Comma-separated list of sources to skip")
+	return cmd
+}
+
+// listSources prints every registered source along with its credential requirements, marking the
+// opt-in sources that only run when selected by name.
+// The configuration is optional here; if it cannot be loaded, credential status is shown as unknown.
+func listSources() {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		cfg = nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCREDENTIALS\tSTATUS")
+	for _, s := range api.Sources() {
+		creds := s.RequiredCredentials()
+		status := "ready"
+		switch {
+		case cfg == nil && len(creds) > 0:
+			status = "unknown (no config)"
+		case cfg != nil:
+			if missing := api.MissingCredentials(s, cfg); len(missing) > 0 {
+				status = "missing " + strings.Join(missing, ", ")
+			}
+		}
+		if api.IsOptIn(s) {
+			status += " (opt-in: select with --sources)"
+		}
+		credList := "-"
+		if len(creds) > 0 {
+			credList = strings.Join(creds, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name(), credList, status)
+	}
+	w.Flush()
+}
+
+// runDoctor loads the configuration, reports the status of every selected source and returns
+// whether at least one source is usable.
+func runDoctor() bool {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		fmt.Printf("[!] Configuration: failed to load: %v\n", err)
+		return false
+	}
+	if err := config.Validate(cfg); err != nil {
+		fmt.Printf("[!] Configuration: invalid: %v\n", err)
+		return false
+	}
+	fmt.Println("[+] Configuration: OK")
+
+	if len(sources) > 0 {
+		cfg.Sources = sources
+	}
+	if len(excludeSources) > 0 {
+		cfg.ExcludeSources = excludeSources
+	}
+	selected, err := api.SelectSources(cfg.Sources, cfg.ExcludeSources)
+	if err != nil {
+		fmt.Printf("[!] Source selection: %v\n", err)
+		return false
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tSTATUS\tDETAILS")
+	usable := 0
+	for _, s := range selected {
+		if missing := api.MissingCredentials(s, cfg); len(missing) > 0 {
+			fmt.Fprintf(w, "%s\tdisabled\tmissing %s\n", s.Name(), strings.Join(missing, ", "))
+			continue
+		}
+		usable++
+		details := "no credentials required"
+		if creds := s.RequiredCredentials(); len(creds) > 0 {
+			var pools []string
+			for _, key := range creds {
+				pools = append(pools, fmt.Sprintf("%s: %d key(s)", key, len(cfg.CredentialPool(key))))
+			}
+			details = "credentials configured (" + strings.Join(pools, ", ") + ")"
+		}
+		fmt.Fprintf(w, "%s\tusable\t%s\n", s.Name(), details)
+	}
+	w.Flush()
+
+	if usable == 0 {
+		fmt.Println("[!] No usable sources.")
+		return false
+	}
+	fmt.Printf("[+] %d of %d selected sources usable.\n", usable, len(selected))
+	return true
+}
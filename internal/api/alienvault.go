@@ -5,15 +5,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/fatih/color"
 	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/psl"
+	"github.com/grumpzsux/goParams/internal/result"
 )
 
 // AlienVaultResponse represents the JSON response structure from Alien Vault OTX.
@@ -21,6 +24,7 @@ type AlienVaultResponse struct {
 	FullSize int `json:"full_size"`
 	UrlList  []struct {
 		URL      string      `json:"url"`
+		Date     string      `json:"date"`
 		HTTPCode interface{} `json:"httpcode"` // May be int or string.
 	} `json:"url_list"`
 }
@@ -28,23 +32,40 @@ type AlienVaultResponse struct {
 // BaseAlienVaultURL is the API endpoint template.
 const BaseAlienVaultURL = "https://otx.alienvault.com/api/v1/indicators/{TYPE}/{DOMAIN}/url_list?limit=500"
 
-// getIndicatorType returns "hostname" if the domain appears to be a subdomain.
-func getIndicatorType(domain string) string {
-	parts := strings.Split(domain, ".")
-	if len(parts) > 2 {
-		return "hostname"
+func init() {
+	Register(NewSource("alienvault", FetchAlienVault, config.KeyAlienVaultAPIKey))
+}
+
+// alienVaultAuth sends pooled OTX keys in the X-OTX-API-KEY header.
+var alienVaultAuth = KeyAuth{Credential: config.KeyAlienVaultAPIKey, Header: "X-OTX-API-KEY"}
+
+// alienVaultIndicator returns the OTX indicator type and value to query for a target.
+// A registrable domain (per the Public Suffix List) is queried as a "domain", which covers all of
+// its hostnames; anything below it is queried as a "hostname". With includeSubdomains the
+// registrable domain is always queried and out-of-scope hosts are dropped downstream.
+func alienVaultIndicator(domain string, includeSubdomains bool) (string, string) {
+	host, err := psl.Normalize(domain)
+	if err != nil {
+		return "hostname", domain
 	}
-	return "domain"
+	apex, err := psl.RegistrableDomain(host)
+	if err != nil {
+		return "hostname", host
+	}
+	if includeSubdomains || apex == host {
+		return "domain", apex
+	}
+	return "hostname", host
 }
 
-// FetchAlienVault retrieves URLs from Alien Vault OTX for the given domain.
-func FetchAlienVault(ctx context.Context, domain string, cfg *config.Config) ([]string, error) {
-	if cfg.AlienVaultAPIKey == "" {
+// FetchAlienVault retrieves URLs from Alien Vault OTX for the given domain and sends them to out.
+func FetchAlienVault(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error {
+	if cfg.Credential(config.KeyAlienVaultAPIKey) == "" {
 		color.Yellow("No Alien Vault API key provided. Skipping Alien Vault lookup for %s", domain)
-		return nil, nil
+		return nil
 	}
-	indicatorType := getIndicatorType(domain)
-	escapedDomain := url.QueryEscape(domain)
+	indicatorType, indicator := alienVaultIndicator(domain, cfg.IncludeSubdomains)
+	escapedDomain := url.QueryEscape(indicator)
 	baseURL := strings.Replace(BaseAlienVaultURL, "{TYPE}", indicatorType, 1)
 	baseURL = strings.Replace(baseURL, "{DOMAIN}", escapedDomain, 1)
 
@@ -52,71 +73,74 @@ func FetchAlienVault(ctx context.Context, domain string, cfg *config.Config) ([]
 	initialURL := baseURL + "&showNumPages=True"
 	color.Blue("[*] Fetching Alien Vault page count from: %s", initialURL)
 
-	resp, err := GetWithRandomUA(ctx, initialURL, cfg)
+	resp, err := GetWithAPIKey(ctx, initialURL, cfg, alienVaultAuth, nil)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching Alien Vault initial page: %w", err)
+		return fmt.Errorf("error fetching Alien Vault initial page: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusTooManyRequests {
-		return nil, errors.New("Alien Vault rate limit reached (429)")
+		return errors.New("Alien Vault rate limit reached (429)")
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Alien Vault returned status code %d", resp.StatusCode)
+		return fmt.Errorf("Alien Vault returned status code %d", resp.StatusCode)
 	}
 
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	var initResp AlienVaultResponse
 	if err := json.Unmarshal(bodyBytes, &initResp); err != nil {
-		return nil, fmt.Errorf("error parsing Alien Vault initial JSON: %w", err)
+		return fmt.Errorf("error parsing Alien Vault initial JSON: %w", err)
 	}
 
 	totalURLs := initResp.FullSize
 	if totalURLs == 0 {
 		color.Yellow("Alien Vault returned zero results for %s", domain)
-		return nil, nil
+		return nil
 	}
 
 	totalPages := int(math.Ceil(float64(totalURLs) / 500.0))
 	color.Blue("[*] Alien Vault reports %d results over %d pages", totalURLs, totalPages)
 
+	// Bound the number of pages requested at once by the configured concurrency.
+	sem := make(chan struct{}, cfg.Concurrency)
 	var wg sync.WaitGroup
-	var mu sync.Mutex
-	urlSet := make(map[string]struct{})
 	for page := 1; page <= totalPages; page++ {
 		wg.Add(1)
+		sem <- struct{}{}
 		pageURL := baseURL + "&page=" + fmt.Sprintf("%d", page)
 		go func(pageURL string) {
 			defer wg.Done()
-			pageURLs, err := processAlienVaultPage(ctx, pageURL, domain, cfg)
+			defer func() { <-sem }()
+			_, err := fetchPage(ctx, out, pageURL, func(ctx context.Context) (string, error) {
+				pageResults, err := processAlienVaultPage(ctx, pageURL, cfg)
+				if err != nil {
+					return "", err
+				}
+				for _, r := range pageResults {
+					if !emit(ctx, out, r) {
+						break
+					}
+				}
+				return "", nil
+			})
 			if err != nil {
 				color.Yellow("Error processing Alien Vault page %s: %v", pageURL, err)
-				return
+				reportError(ctx, fmt.Errorf("page %s: %w", pageURL, err))
 			}
-			mu.Lock()
-			for _, u := range pageURLs {
-				urlSet[u] = struct{}{}
-			}
-			mu.Unlock()
 		}(pageURL)
 	}
 	wg.Wait()
-
-	var results []string
-	for u := range urlSet {
-		results = append(results, u)
-	}
-	return results, nil
+	return ctx.Err()
 }
 
-// processAlienVaultPage makes a request to the provided page URL and returns a slice of valid URLs.
-func processAlienVaultPage(ctx context.Context, pageURL, targetDomain string, cfg *config.Config) ([]string, error) {
+// processAlienVaultPage makes a request to the provided page URL and returns the valid URLs with their metadata.
+func processAlienVaultPage(ctx context.Context, pageURL string, cfg *config.Config) ([]result.Result, error) {
 	color.Blue("[*] Processing Alien Vault page: %s", pageURL)
-	resp, err := GetWithRandomUA(ctx, pageURL, cfg)
+	resp, err := GetWithAPIKey(ctx, pageURL, cfg, alienVaultAuth, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error requesting Alien Vault page: %w", err)
 	}
@@ -129,7 +153,7 @@ func processAlienVaultPage(ctx context.Context, pageURL, targetDomain string, cf
 		return nil, fmt.Errorf("Alien Vault page returned status code %d", resp.StatusCode)
 	}
 
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
@@ -143,16 +167,29 @@ func processAlienVaultPage(ctx context.Context, pageURL, targetDomain string, cf
 		return nil, fmt.Errorf("error parsing Alien Vault page JSON: %w", err)
 	}
 
-	var urlsFound []string
+	var urlsFound []result.Result
 	for _, entry := range avResp.UrlList {
 		foundURL := entry.URL
 		if foundURL == "" {
 			continue
 		}
-		// Basic filtering: include only URLs with query parameters and matching target domain.
-		if strings.Contains(foundURL, "?") && strings.Contains(strings.ToLower(foundURL), strings.ToLower(targetDomain)) {
-			urlsFound = append(urlsFound, foundURL)
+		// Basic filtering: include only URLs with query parameters. Host filtering is left to the scope checks.
+		if strings.Contains(foundURL, "?") {
+			r := result.Result{URL: foundURL, Status: parseHTTPCode(entry.HTTPCode)}
+			r.SetSeen(result.ParseTimestamp(entry.Date))
+			urlsFound = append(urlsFound, r)
 		}
 	}
 	return urlsFound, nil
 }
+
+// parseHTTPCode converts the OTX httpcode field, which may be a number or a string, to a status code.
+func parseHTTPCode(v interface{}) int {
+	switch code := v.(type) {
+	case float64:
+		return result.ParseStatus(strconv.Itoa(int(code)))
+	case string:
+		return result.ParseStatus(code)
+	}
+	return 0
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/grumpzsux/goParams/internal/cache"
+	"github.com/grumpzsux/goParams/internal/config"
+)
+
+var (
+	cacheMu       sync.RWMutex
+	responseCache *cache.Cache
+	refreshCache  bool
+)
+
+// UseCache makes GetWithRandomUA, GetWithHeaders and GetWithAPIKey serve successful responses from c
+// within each source's TTL (see config.Config.CacheTTLFor), and store the responses they fetch.
+// With refresh, cached responses are ignored but fresh ones are still stored. A nil c disables caching.
+func UseCache(c *cache.Cache, refresh bool) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	responseCache, refreshCache = c, refresh
+}
+
+// cachedResponse returns the cached response for rawURL if the source's TTL allows it.
+func cachedResponse(cfg *config.Config, source, rawURL string) (*http.Response, bool) {
+	cacheMu.RLock()
+	c, refresh := responseCache, refreshCache
+	cacheMu.RUnlock()
+	ttl := cfg.CacheTTLFor(source)
+	if c == nil || refresh || ttl <= 0 {
+		return nil, false
+	}
+	resp, ok := c.Get(cache.Key(rawURL), ttl)
+	if ok {
+		logrus.Debugf("[%s] cache hit for %s", source, rawURL)
+	}
+	return resp, ok
+}
+
+// storeResponse arranges for a successful response to be stored in the cache as its body is read.
+// The entry is only committed if the body is read to the end.
+func storeResponse(cfg *config.Config, source, rawURL string, resp *http.Response) *http.Response {
+	cacheMu.RLock()
+	c := responseCache
+	cacheMu.RUnlock()
+	if c == nil || resp.StatusCode != http.StatusOK || cfg.CacheTTLFor(source) <= 0 {
+		return resp
+	}
+	entry, err := c.Create(cache.Key(rawURL), resp.StatusCode, resp.Header)
+	if err != nil {
+		logrus.Debugf("[%s] cannot cache %s: %v", source, rawURL, err)
+		return resp
+	}
+	resp.Body = &cachingBody{body: resp.Body, entry: entry}
+	return resp
+}
+
+// cachingBody copies a response body into a cache entry while it is read.
+type cachingBody struct {
+	body  io.ReadCloser
+	entry *cache.Entry
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.entry.Write(p[:n])
+	}
+	if err == io.EOF {
+		if commitErr := b.entry.Commit(); commitErr != nil {
+			logrus.Debugf("cannot commit cache entry: %v", commitErr)
+		}
+	}
+	return n, err
+}
+
+func (b *cachingBody) Close() error {
+	b.entry.Abort()
+	return b.body.Close()
+}
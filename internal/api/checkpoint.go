@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// Checkpoint stores the pages each source has completed for a domain, so that an interrupted run can
+// be resumed. It is implemented by checkpoint.Journal.
+type Checkpoint interface {
+	// Page returns the results and next cursor of a completed page.
+	Page(domain, source, page string) (results []result.Result, next string, ok bool)
+	// Record stores a completed page.
+	Record(domain, source, page string, results []result.Result, next string) error
+}
+
+type checkpointContextKey struct{}
+
+// WithCheckpoint returns a context whose sources skip pages already completed in cp, replaying
+// their stored URLs instead, and record every page they complete.
+func WithCheckpoint(ctx context.Context, cp Checkpoint) context.Context {
+	return context.WithValue(ctx, checkpointContextKey{}, cp)
+}
+
+func checkpointFromContext(ctx context.Context) Checkpoint {
+	cp, _ := ctx.Value(checkpointContextKey{}).(Checkpoint)
+	return cp
+}
+
+type targetContextKey struct{}
+
+// withTarget returns a context that records the domain being harvested.
+func withTarget(ctx context.Context, domain string) context.Context {
+	return context.WithValue(ctx, targetContextKey{}, domain)
+}
+
+func targetFromContext(ctx context.Context) string {
+	domain, _ := ctx.Value(targetContextKey{}).(string)
+	return domain
+}
+
+// pageRecorder collects the results emitted while a page is being fetched.
+type pageRecorder struct {
+	mu      sync.Mutex
+	results []result.Result
+}
+
+func (p *pageRecorder) add(r result.Result) {
+	p.mu.Lock()
+	p.results = append(p.results, r)
+	p.mu.Unlock()
+}
+
+type pageRecorderContextKey struct{}
+
+// fetchPage runs fetch for one page of a source, identified by page (usually its request URL, which
+// must not contain secrets). With a checkpoint in ctx, a page completed by an earlier run is not
+// fetched again: its stored results are sent to out and its stored next cursor is returned. Otherwise
+// the results fetch emits are recorded, and the page is stored once fetch succeeds.
+func fetchPage(ctx context.Context, out chan<- result.Result, page string, fetch func(ctx context.Context) (next string, err error)) (string, error) {
+	cp := checkpointFromContext(ctx)
+	if cp == nil {
+		return fetch(ctx)
+	}
+	domain, source := targetFromContext(ctx), sourceFromContext(ctx)
+	if results, next, ok := cp.Page(domain, source, page); ok {
+		logrus.Debugf("[%s] replaying %d URLs for %s from checkpoint", source, len(results), page)
+		for _, r := range results {
+			if !emit(ctx, out, r) {
+				return next, ctx.Err()
+			}
+		}
+		return next, nil
+	}
+
+	rec := &pageRecorder{}
+	next, err := fetch(context.WithValue(ctx, pageRecorderContextKey{}, rec))
+	if err != nil || ctx.Err() != nil {
+		return next, err
+	}
+	if err := cp.Record(domain, source, page, rec.results, next); err != nil {
+		logrus.Warnf("Failed to record checkpoint for %s: %v", page, err)
+	}
+	return next, nil
+}
@@ -2,29 +2,157 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/sirupsen/logrus"
+
 	"github.com/grumpzsux/goParams/internal/config"
 	"github.com/grumpzsux/goParams/internal/utils"
 )
 
-// HTTPClient is a shared HTTP client with a timeout.
+// HTTPClient is the shared HTTP client. Its transport is set up by ConfigureHTTP; each request
+// attempt is bounded by the timeout of the source making it (see config.Config.TimeoutFor).
 var HTTPClient = &http.Client{
-	Timeout: 15 * time.Second,
+	Timeout: config.DefaultTimeout,
+}
+
+// cloneValues returns a copy of q that can be modified without affecting the original.
+func cloneValues(q url.Values) url.Values {
+	c := make(url.Values, len(q))
+	for k, v := range q {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
 }
 
 // GetWithRandomUA creates an HTTP GET request with a random User-Agent header from the configuration.
-func GetWithRandomUA(ctx context.Context, url string, cfg *config.Config) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
+// It is GetWithHeaders without extra headers.
+func GetWithRandomUA(ctx context.Context, rawURL string, cfg *config.Config) (*http.Response, error) {
+	return GetWithHeaders(ctx, rawURL, cfg, nil)
+}
+
+// GetWithHeaders creates an HTTP GET request with the given headers and a random User-Agent header
+// from the configuration. Successful responses are served from and stored in the response cache, if
+// enabled (see UseCache). Requests are throttled by the rate limit configured for the source recorded in ctx.
+// Network errors, 429 and 5xx responses are retried with jittered exponential backoff, honouring
+// Retry-After, up to the source's configured number of attempts. When attempts run out the last
+// response is returned so callers can still inspect its status code.
+func GetWithHeaders(ctx context.Context, rawURL string, cfg *config.Config, header http.Header) (*http.Response, error) {
+	return get(ctx, rawURL, cfg, header, nil)
+}
+
+// GetWithAPIKey is GetWithHeaders for APIs that authenticate with a key. Keys are taken round-robin
+// from the credential's pool (see config.Config.CredentialPool) and sent as described by auth.
+// A key that receives a 429, or a 403 whose body reports an exhausted quota, is benched for a while
+// and the request is retried at once with the next key; only when every key is benched does the normal backoff apply.
+// Each key has its own rate limiter at the source's rate_limits value, so a pool of N keys allows
+// N times the requests of a single key. Keys are masked in returned errors and usage is counted per
+// key in the run summary.
+func GetWithAPIKey(ctx context.Context, rawURL string, cfg *config.Config, auth KeyAuth, header http.Header) (*http.Response, error) {
+	return get(ctx, rawURL, cfg, header, &auth)
+}
+
+func get(ctx context.Context, rawURL string, cfg *config.Config, header http.Header, auth *KeyAuth) (*http.Response, error) {
+	source := sourceFromContext(ctx)
+	maxAttempts := cfg.MaxAttemptsFor(source)
+	client := *HTTPClient
+	client.Timeout = cfg.TimeoutFor(source)
+
+	if resp, ok := cachedResponse(cfg, source, rawURL); ok {
+		return resp, nil
+	}
+
+	var pool *keyPool
+	if auth != nil {
+		pool = keyPoolFor(cfg, auth.Credential)
 	}
-	// Pick a random user agent from the configuration.
-	ua := utils.RandomStringFromSlice(cfg.UserAgents)
-	if ua == "" {
-		ua = "Mozilla/5.0 (compatible)"
+
+	for attempt := 1; ; attempt++ {
+		reqURL := rawURL
+		var key *pooledKey
+		limiterKey := ""
+		if pool != nil {
+			if key = pool.acquire(); key == nil {
+				return nil, fmt.Errorf("no %s configured", auth.Credential)
+			}
+			limiterKey = key.value
+		}
+		if err := waitForRateLimit(ctx, cfg, limiterKey); err != nil {
+			return nil, err
+		}
+		if key != nil && auth.Param != "" {
+			u, err := url.Parse(rawURL)
+			if err != nil {
+				return nil, err
+			}
+			q := u.Query()
+			q.Set(auth.Param, key.value)
+			u.RawQuery = q.Encode()
+			reqURL = u.String()
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		// Pick a random user agent from the configuration.
+		ua := utils.RandomStringFromSlice(cfg.UserAgents)
+		if ua == "" {
+			ua = "Mozilla/5.0 (compatible)"
+		}
+		req.Header.Set("User-Agent", ua)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		if key != nil && auth.Header != "" {
+			req.Header.Set(auth.Header, key.value)
+		}
+
+		resp, err := client.Do(req)
+		if key != nil {
+			if err != nil {
+				// Transport errors embed the request URL, which may carry the key.
+				err = errors.New(strings.ReplaceAll(err.Error(), key.value, "REDACTED"))
+			}
+			bench, quota := time.Duration(0), false
+			if err == nil {
+				bench, quota = quotaBench(resp)
+			}
+			recordKeyUse(ctx, auth.Credential, key.value, quota)
+			if quota {
+				available := pool.bench(key, bench)
+				logrus.Debugf("[%s] key %s returned %d; benched for %s", source, maskKey(key.value), resp.StatusCode, bench)
+				if available {
+					// Retry straight away with the next key without using up an attempt.
+					discardBody(resp)
+					attempt--
+					continue
+				}
+			}
+		}
+		if err == nil && !isRetryableStatus(resp.StatusCode) {
+			return storeResponse(cfg, source, rawURL, resp), nil
+		}
+		if attempt >= maxAttempts || ctx.Err() != nil {
+			return resp, err
+		}
+
+		delay := backoffDelay(attempt)
+		if err != nil {
+			logrus.Debugf("[%s] attempt %d/%d for %s failed: %v; retrying in %s", source, attempt, maxAttempts, rawURL, err, delay)
+		} else {
+			if ra, ok := retryAfter(resp); ok {
+				delay = ra
+			}
+			logrus.Debugf("[%s] attempt %d/%d for %s returned %d; retrying in %s", source, attempt, maxAttempts, rawURL, resp.StatusCode, delay)
+			discardBody(resp)
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
 	}
-	req.Header.Set("User-Agent", ua)
-	return HTTPClient.Do(req)
 }
@@ -9,10 +9,15 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/result"
 )
 
 // CommonCrawlEntry represents one record from the Common Crawl index.
@@ -24,41 +29,325 @@ type CommonCrawlEntry struct {
 	Digest    string `json:"digest"`
 }
 
-// BaseIndexURL is the Common Crawl index URL. (This may be updated periodically.)
-const BaseIndexURL = "http://index.commoncrawl.org/CC-MAIN-2019-51-index"
+// CommonCrawlIndex describes one crawl as listed in collinfo.json.
+type CommonCrawlIndex struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	CDXAPI string `json:"cdx-api"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// CommonCrawlCollInfoURL lists every available Common Crawl index, newest first.
+const CommonCrawlCollInfoURL = "https://index.commoncrawl.org/collinfo.json"
+
+// DefaultCCIndexes is the crawl selection used when cc_indexes is not configured: the newest three crawls.
+const DefaultCCIndexes = "3"
+
+func init() {
+	Register(NewSource("commoncrawl", FetchCommonCrawl))
+}
+
+var (
+	collInfoMu sync.Mutex
+	collInfo   []CommonCrawlIndex
+)
+
+// loadCollInfo fetches the list of Common Crawl indexes once per run.
+func loadCollInfo(ctx context.Context, cfg *config.Config) ([]CommonCrawlIndex, error) {
+	collInfoMu.Lock()
+	defer collInfoMu.Unlock()
+	if collInfo != nil {
+		return collInfo, nil
+	}
+
+	color.Blue("[*] Fetching Common Crawl index list from: %s", CommonCrawlCollInfoURL)
+	resp, err := GetWithRandomUA(ctx, CommonCrawlCollInfoURL, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching Common Crawl index list: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Common Crawl index list returned status code: %d", resp.StatusCode)
+	}
+
+	var indexes []CommonCrawlIndex
+	if err := json.NewDecoder(resp.Body).Decode(&indexes); err != nil {
+		return nil, fmt.Errorf("error parsing Common Crawl index list: %w", err)
+	}
+	// collinfo.json is newest first already, but do not rely on it.
+	sort.SliceStable(indexes, func(i, j int) bool { return indexes[i].ID > indexes[j].ID })
+	collInfo = indexes
+	return collInfo, nil
+}
+
+// ccIndexSpec is a parsed crawl selection.
+type ccIndexSpec struct {
+	all      bool
+	latest   int
+	from, to time.Time
+	ids      []string
+}
+
+// parseCCIndexSpec parses a crawl selection. Accepted forms are:
+//   - "all": every available crawl
+//   - "N": the newest N crawls
+//   - "YYYY-MM-DD..YYYY-MM-DD": crawls overlapping the date range (either end may be omitted)
+//   - "CC-MAIN-2024-33,CC-MAIN-2024-30": an explicit list of crawl IDs
+func parseCCIndexSpec(spec string) (ccIndexSpec, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		spec = DefaultCCIndexes
+	}
+	if strings.EqualFold(spec, "all") {
+		return ccIndexSpec{all: true}, nil
+	}
+	if n, err := strconv.Atoi(spec); err == nil {
+		if n <= 0 {
+			return ccIndexSpec{}, fmt.Errorf("invalid Common Crawl index count %d", n)
+		}
+		return ccIndexSpec{latest: n}, nil
+	}
+	if strings.Contains(spec, "..") {
+		parts := strings.SplitN(spec, "..", 2)
+		var s ccIndexSpec
+		var err error
+		if parts[0] != "" {
+			if s.from, err = time.Parse("2006-01-02", strings.TrimSpace(parts[0])); err != nil {
+				return ccIndexSpec{}, fmt.Errorf("invalid Common Crawl start date: %w", err)
+			}
+		}
+		if parts[1] != "" {
+			if s.to, err = time.Parse("2006-01-02", strings.TrimSpace(parts[1])); err != nil {
+				return ccIndexSpec{}, fmt.Errorf("invalid Common Crawl end date: %w", err)
+			}
+			// Include the whole end day.
+			s.to = s.to.Add(24*time.Hour - time.Nanosecond)
+		}
+		return s, nil
+	}
+	var s ccIndexSpec
+	for _, id := range strings.Split(spec, ",") {
+		id = strings.ToUpper(strings.TrimSpace(id))
+		if id == "" {
+			continue
+		}
+		if !strings.HasPrefix(id, "CC-MAIN-") {
+			return ccIndexSpec{}, fmt.Errorf("invalid Common Crawl index %q", id)
+		}
+		s.ids = append(s.ids, id)
+	}
+	if len(s.ids) == 0 {
+		return ccIndexSpec{}, fmt.Errorf("invalid Common Crawl index selection %q", spec)
+	}
+	return s, nil
+}
+
+// ccIndexRange returns the time span covered by a crawl. It prefers the from/to fields of
+// collinfo.json and falls back to the year and ISO week encoded in the ID (CC-MAIN-YYYY-WW).
+func ccIndexRange(idx CommonCrawlIndex) (time.Time, time.Time, bool) {
+	from, errFrom := time.Parse("2006-01-02", prefix(idx.From, 10))
+	to, errTo := time.Parse("2006-01-02", prefix(idx.To, 10))
+	if errFrom == nil && errTo == nil {
+		return from, to.Add(24*time.Hour - time.Nanosecond), true
+	}
+	parts := strings.Split(idx.ID, "-")
+	if len(parts) != 4 {
+		return time.Time{}, time.Time{}, false
+	}
+	year, errYear := strconv.Atoi(parts[2])
+	week, errWeek := strconv.Atoi(parts[3])
+	if errYear != nil || errWeek != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, (week-1)*7)
+	return start, start.AddDate(0, 0, 7), true
+}
+
+// prefix returns the first n bytes of s, or s itself if it is shorter.
+func prefix(s string, n int) string {
+	if len(s) < n {
+		return s
+	}
+	return s[:n]
+}
+
+// selectCCIndexes picks the crawls described by spec from the available (newest first) indexes.
+func selectCCIndexes(available []CommonCrawlIndex, spec ccIndexSpec) ([]CommonCrawlIndex, error) {
+	switch {
+	case spec.all:
+		return available, nil
+	case spec.latest > 0:
+		if spec.latest > len(available) {
+			return available, nil
+		}
+		return available[:spec.latest], nil
+	case len(spec.ids) > 0:
+		byID := make(map[string]CommonCrawlIndex, len(available))
+		for _, idx := range available {
+			byID[idx.ID] = idx
+		}
+		var selected []CommonCrawlIndex
+		for _, id := range spec.ids {
+			idx, ok := byID[id]
+			if !ok {
+				return nil, fmt.Errorf("unknown Common Crawl index %q", id)
+			}
+			selected = append(selected, idx)
+		}
+		return selected, nil
+	}
+
+	var selected []CommonCrawlIndex
+	for _, idx := range available {
+		from, to, ok := ccIndexRange(idx)
+		if !ok {
+			continue
+		}
+		if (!spec.from.IsZero() && to.Before(spec.from)) || (!spec.to.IsZero() && from.After(spec.to)) {
+			continue
+		}
+		selected = append(selected, idx)
+	}
+	return selected, nil
+}
+
+// ccQuery builds the index query for a domain (exclude "warc/revisit" and status 404).
+// With includeSubdomains the query uses matchType=domain to cover every subdomain.
+func ccQuery(domain string, includeSubdomains bool) url.Values {
+	q := url.Values{}
+	if includeSubdomains {
+		q.Set("url", domain)
+		q.Set("matchType", "domain")
+	} else {
+		q.Set("url", domain+"/*")
+	}
+	q.Set("output", "json")
+	q.Set("fl", "timestamp,url,mime,status,digest")
+	// Filters are exact matches ("!" negates) or, with "~", substring matches; a plain "url:"
+	// filter is a regular expression, used here to keep only URLs with a query string.
+	q.Add("filter", "!mime:warc/revisit")
+	q.Add("filter", "!status:404")
+	q.Add("filter", `url:.*\?`)
+	return q
+}
+
+// ccNumPages asks an index server how many pages the query spans.
+func ccNumPages(ctx context.Context, idx CommonCrawlIndex, q url.Values, cfg *config.Config) (int, error) {
+	pq := cloneValues(q)
+	pq.Set("showNumPages", "true")
+	apiURL := idx.CDXAPI + "?" + pq.Encode()
+
+	resp, err := GetWithRandomUA(ctx, apiURL, cfg)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching Common Crawl page count for %s: %w", idx.ID, err)
+	}
+	defer resp.Body.Close()
+	// The index server answers 404 when the domain has no captures in this crawl.
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Common Crawl %s returned status code: %d", idx.ID, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return 0, err
+	}
+	var pages struct {
+		Pages int `json:"pages"`
+	}
+	if err := json.Unmarshal(body, &pages); err != nil {
+		return 0, fmt.Errorf("error parsing Common Crawl page count for %s: %w", idx.ID, err)
+	}
+	return pages.Pages, nil
+}
 
-// FetchCommonCrawl queries the Common Crawl index for the given domain and returns URLs with parameters.
-func FetchCommonCrawl(ctx context.Context, domain string, cfg *config.Config) ([]string, error) {
-	// Define filters (exclude "warc/revisit" and status 404).
-	filterMIME := "&filter=!~mime:(warc/revisit)"
-	filterCode := "&filter=!~status:(404)"
-	filterKeywords := ""
+// FetchCommonCrawl queries the selected Common Crawl indexes for the given domain and sends URLs with parameters to out.
+// Crawls are chosen with cfg.CCIndexes (see parseCCIndexSpec); every page of every crawl is fetched
+// concurrently, bounded by cfg.Concurrency, and results from all crawls are merged downstream.
+func FetchCommonCrawl(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error {
+	spec, err := parseCCIndexSpec(cfg.CCIndexes)
+	if err != nil {
+		return err
+	}
+	available, err := loadCollInfo(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	indexes, err := selectCCIndexes(available, spec)
+	if err != nil {
+		return err
+	}
+	if len(indexes) == 0 {
+		color.Yellow("No Common Crawl indexes match the selection %q", cfg.CCIndexes)
+		return nil
+	}
 
-	escapedDomain := url.QueryEscape(domain + "/*")
-	queryParams := fmt.Sprintf("?output=json&fl=timestamp,url,mime,status,digest&url=%s", escapedDomain)
-	fullURL := BaseIndexURL + queryParams + filterMIME + filterCode + filterKeywords
-	color.Blue("[*] Fetching from Common Crawl: %s", fullURL)
+	q := ccQuery(domain, cfg.IncludeSubdomains)
+	var pageURLs []string
+	for _, idx := range indexes {
+		numPages, err := ccNumPages(ctx, idx, q, cfg)
+		if err != nil {
+			color.Yellow("%v", err)
+			reportError(ctx, err)
+			continue
+		}
+		for page := 0; page < numPages; page++ {
+			pq := cloneValues(q)
+			pq.Set("page", strconv.Itoa(page))
+			pageURLs = append(pageURLs, idx.CDXAPI+"?"+pq.Encode())
+		}
+	}
+	color.Blue("[*] Common Crawl reports %d pages across %d indexes for %s", len(pageURLs), len(indexes), domain)
 
-	resp, err := GetWithRandomUA(ctx, fullURL, cfg)
+	sem := make(chan struct{}, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for _, pageURL := range pageURLs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(pageURL string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			_, err := fetchPage(ctx, out, pageURL, func(ctx context.Context) (string, error) {
+				return "", fetchCommonCrawlPage(ctx, pageURL, domain, cfg, out)
+			})
+			if err != nil {
+				color.Yellow("Error processing Common Crawl page %s: %v", pageURL, err)
+				reportError(ctx, fmt.Errorf("page %s: %w", pageURL, err))
+			}
+		}(pageURL)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// fetchCommonCrawlPage requests a single index page and streams its entries to out.
+func fetchCommonCrawlPage(ctx context.Context, pageURL, domain string, cfg *config.Config, out chan<- result.Result) error {
+	color.Blue("[*] Fetching from Common Crawl: %s", pageURL)
+
+	resp, err := GetWithRandomUA(ctx, pageURL, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching from Common Crawl: %w", err)
+		return fmt.Errorf("error fetching from Common Crawl: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == 429 {
-		return nil, errors.New("Common Crawl rate limit reached (429)")
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return errors.New("Common Crawl rate limit reached (429)")
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Common Crawl returned status code: %d", resp.StatusCode)
+		return fmt.Errorf("Common Crawl returned status code: %d", resp.StatusCode)
 	}
 
 	reader := bufio.NewReader(resp.Body)
-	var urlsFound []string
 	for {
 		line, err := reader.ReadString('\n')
 		if err != nil && err != io.EOF {
-			color.Yellow("Error reading a line: %v", err)
-			break
+			// Fail the page rather than checkpoint it half-read.
+			return fmt.Errorf("error reading Common Crawl response: %w", err)
 		}
 		line = strings.TrimSpace(line)
 		if line == "" {
@@ -73,19 +362,23 @@ func FetchCommonCrawl(ctx context.Context, domain string, cfg *config.Config) ([
 			break
 		}
 		var entry CommonCrawlEntry
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			color.Yellow("Failed to parse JSON from line: %v", err)
+		if jsonErr := json.Unmarshal([]byte(line), &entry); jsonErr != nil {
+			color.Yellow("Failed to parse JSON from line: %v", jsonErr)
 			if err == io.EOF {
 				break
 			}
 			continue
 		}
 		if strings.Contains(entry.URL, "?") {
-			urlsFound = append(urlsFound, entry.URL)
+			r := result.Result{URL: entry.URL, Status: result.ParseStatus(entry.Status), MIME: entry.Mime}
+			r.SetSeen(result.ParseTimestamp(entry.Timestamp))
+			if !emit(ctx, out, r) {
+				return ctx.Err()
+			}
 		}
 		if err == io.EOF {
 			break
 		}
 	}
-	return urlsFound, nil
+	return nil
 }
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCCQuery(t *testing.T) {
+	tests := []struct {
+		name              string
+		includeSubdomains bool
+		want              string
+	}{
+		{
+			name: "domain",
+			want: "filter=%21mime%3Awarc%2Frevisit&filter=%21status%3A404&filter=url%3A.%2A%5C%3F" +
+				"&fl=timestamp%2Curl%2Cmime%2Cstatus%2Cdigest&output=json&url=example.com%2F%2A",
+		},
+		{
+			name:              "subdomains",
+			includeSubdomains: true,
+			want: "filter=%21mime%3Awarc%2Frevisit&filter=%21status%3A404&filter=url%3A.%2A%5C%3F" +
+				"&fl=timestamp%2Curl%2Cmime%2Cstatus%2Cdigest&matchType=domain&output=json&url=example.com",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q := ccQuery("example.com", tc.includeSubdomains)
+			if got := q.Encode(); got != tc.want {
+				t.Errorf("ccQuery encoded = %s, want %s", got, tc.want)
+			}
+			// The url filter must be a regular expression: "~" would make it a substring match.
+			want := []string{"!mime:warc/revisit", "!status:404", `url:.*\?`}
+			if got := q["filter"]; !reflect.DeepEqual(got, want) {
+				t.Errorf("filters = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -2,66 +2,65 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
-	"github.com/sirupsen/logrus"
 	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/result"
+	"github.com/sirupsen/logrus"
 )
 
 // FetchFunc defines the signature for API fetching functions.
-type FetchFunc func(ctx context.Context, domain string, cfg *config.Config) ([]string, error)
+// Implementations send each URL they find to out and return once the source is exhausted.
+type FetchFunc func(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error
 
-// FetchAll queries all available data sources concurrently and returns a deduplicated list of URLs.
-// If one source fails (for example, Wayback times out), its error is logged as a warning while continuing with the others.
-func FetchAll(ctx context.Context, domain string, cfg *config.Config) ([]string, error) {
-	apis := []FetchFunc{
-		FetchWayback,     // Extended timeout is handled within FetchWayback.
-		FetchCommonCrawl, // Likewise, these functions accept context.
-		FetchVirusTotal,
-		FetchAlienVault,
+// FetchAll queries the selected data sources concurrently and streams their URLs to out.
+// Sources are chosen from the registry using cfg.Sources and cfg.ExcludeSources; sources whose
+// credentials are missing are skipped (see EnabledSources).
+// FetchAll returns once every source has finished; it does not close out.
+// If one source fails (for example, Wayback times out), its error is logged as a warning and recorded in the
+// Summary attached to ctx (see WithSummary) while continuing with the others.
+func FetchAll(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error {
+	sources, _, err := EnabledSources(cfg)
+	if err != nil {
+		return err
 	}
 
 	var wg sync.WaitGroup
-	urlCh := make(chan []string)
-	errCh := make(chan error, len(apis))
-
-	for _, apiFunc := range apis {
+	for _, src := range sources {
 		wg.Add(1)
-		go func(f FetchFunc) {
+		go func(s Source) {
 			defer wg.Done()
-			urls, err := f(ctx, domain, cfg)
-			if err != nil {
-				errCh <- err
-				return
+			sourceCtx := withSource(withTarget(ctx, domain), s.Name())
+			if err := s.Fetch(sourceCtx, domain, cfg, out); err != nil {
+				reportError(sourceCtx, fmt.Errorf("%s: %w", domain, err))
+				logrus.Warnf("An API error occurred: %v", fmt.Errorf("%s: %w", s.Name(), err))
 			}
-			urlCh <- urls
-		}(apiFunc)
-	}
-
-	// Close channels once all goroutines have finished.
-	go func() {
-		wg.Wait()
-		close(urlCh)
-		close(errCh)
-	}()
-
-	// Collect results.
-	urlSet := make(map[string]struct{})
-	for urls := range urlCh {
-		for _, u := range urls {
-			urlSet[u] = struct{}{}
-		}
+		}(src)
 	}
+	wg.Wait()
+	return nil
+}
 
-	// Log any errors encountered.
-	for err := range errCh {
-		logrus.Warnf("An API error occurred: %v", err)
+// emit sends r to out unless the context is cancelled first, counting it in the run summary and
+// recording it for the checkpoint of the page being fetched (see fetchPage).
+// The name of the source recorded in ctx is added to r.Sources.
+// It returns false if the caller should stop producing URLs.
+func emit(ctx context.Context, out chan<- result.Result, r result.Result) bool {
+	source := sourceFromContext(ctx)
+	if source != "" && len(r.Sources) == 0 {
+		r.Sources = []string{source}
 	}
-
-	// Convert set to slice.
-	var results []string
-	for u := range urlSet {
-		results = append(results, u)
+	select {
+	case out <- r:
+		if s := summaryFromContext(ctx); s != nil {
+			s.addURL(source)
+		}
+		if rec, ok := ctx.Value(pageRecorderContextKey{}).(*pageRecorder); ok {
+			rec.add(r)
+		}
+		return true
+	case <-ctx.Done():
+		return false
 	}
-	return results, nil
 }
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/psl"
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// expandFiles expands glob patterns in a list of files. Entries that are not patterns, or match
+// nothing, are kept so that opening them reports the problem.
+func expandFiles(patterns []string) []string {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil || len(matches) == 0 {
+			files = append(files, pattern)
+			continue
+		}
+		files = append(files, matches...)
+	}
+	return files
+}
+
+// fileReader streams the results recorded in one file to fn until the file ends or fn returns false.
+type fileReader func(path string, fn func(result.Result) bool) error
+
+// fetchFiles reads files with readFile for the named source and sends the URLs with query strings on
+// the domain or its subdomains to out as they are read. Targets harvested at the same time share each
+// pass over the files (see readFiles). what names the kind of file in progress and error messages;
+// a file that cannot be read is reported and skipped.
+func fetchFiles(ctx context.Context, source, domain string, files []string, what string, readFile fileReader, cfg *config.Config, out chan<- result.Result) error {
+	return readFiles(ctx, source, domain, cfg.Concurrency, func(ctx context.Context, route func(result.Result) bool) {
+		for _, path := range files {
+			color.Blue("[*] Reading %s: %s", what, path)
+			err := readFile(path, func(r result.Result) bool {
+				if !strings.Contains(r.URL, "?") {
+					return ctx.Err() == nil
+				}
+				return route(r)
+			})
+			if err != nil {
+				color.Yellow("Error reading %s %s: %v", what, path, err)
+				reportError(ctx, err)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}, out)
+}
+
+type runTargetsContextKey struct{}
+
+// runTargets holds the targets of a run and the file reads their sources share.
+type runTargets struct {
+	targets map[string]struct{}
+	count   int // Number of targets in the run, counting repeats.
+	mu      sync.Mutex
+	reads   map[string]*sharedRead // By source name.
+}
+
+// WithTargets returns a context for a run over targets. Sources that read local files use it to share
+// passes over their files between the targets being harvested at the same time (see readFiles).
+func WithTargets(ctx context.Context, targets []string) context.Context {
+	run := &runTargets{
+		targets: make(map[string]struct{}, len(targets)),
+		count:   len(targets),
+		reads:   make(map[string]*sharedRead),
+	}
+	for _, t := range targets {
+		run.targets[t] = struct{}{}
+	}
+	return context.WithValue(ctx, runTargetsContextKey{}, run)
+}
+
+// fileSubscriber is a target taking part in a pass over a source's files.
+type fileSubscriber struct {
+	ctx     context.Context
+	domain  string
+	results chan result.Result // Closed when the pass ends.
+}
+
+// sharedRead schedules the passes over one source's files. A pass starts once every target that can be
+// in progress (up to the run's concurrency) has asked for the files, and serves all of them; targets
+// that ask while a pass is running wait for the next one.
+type sharedRead struct {
+	mu      sync.Mutex
+	read    func(ctx context.Context, route func(result.Result) bool)
+	limit   int // Targets in progress at once.
+	left    int // Targets that have not yet been served.
+	waiting []*fileSubscriber
+	reading bool
+}
+
+// join adds s to the next pass, starting it if no other targets are expected to join.
+func (sr *sharedRead) join(s *fileSubscriber) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.waiting = append(sr.waiting, s)
+	sr.startIfReady()
+}
+
+// startIfReady starts a pass for the waiting targets once all the targets that can be in progress are
+// waiting. sr.mu must be held.
+func (sr *sharedRead) startIfReady() {
+	want := sr.limit
+	if sr.left < want {
+		want = sr.left
+	}
+	if sr.reading || len(sr.waiting) == 0 || len(sr.waiting) < want {
+		return
+	}
+	subs := sr.waiting
+	sr.waiting = nil
+	sr.left -= len(subs)
+	sr.reading = true
+	go func() {
+		runPass(sr.read, subs)
+		sr.mu.Lock()
+		sr.reading = false
+		sr.startIfReady()
+		sr.mu.Unlock()
+	}()
+}
+
+// runPass calls read once and sends each URL it finds to every subscriber the URL is on (the
+// subscriber's domain or one of its subdomains), closing their channels when read returns.
+func runPass(read func(ctx context.Context, route func(result.Result) bool), subs []*fileSubscriber) {
+	byDomain := make(map[string][]*fileSubscriber, len(subs))
+	for _, s := range subs {
+		byDomain[s.domain] = append(byDomain[s.domain], s)
+	}
+	ctx := subs[0].ctx
+	read(ctx, func(r result.Result) bool {
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			return ctx.Err() == nil
+		}
+		host, err := psl.Normalize(u.Hostname())
+		if err != nil {
+			return ctx.Err() == nil
+		}
+		// Try the host and each of its parent domains as a subscriber's domain.
+		for {
+			for _, s := range byDomain[host] {
+				select {
+				case s.results <- r:
+				case <-s.ctx.Done():
+				}
+			}
+			i := strings.IndexByte(host, '.')
+			if i < 0 {
+				break
+			}
+			host = host[i+1:]
+		}
+		return ctx.Err() == nil
+	})
+	for _, s := range subs {
+		close(s.results)
+	}
+}
+
+// readFiles sends the URLs on domain or its subdomains that read finds in the named source's files to
+// out, as read finds them. read passes each URL to route and stops when route returns false.
+// Targets of the run in ctx (see WithTargets) that are harvested at the same time share a single call
+// of read, so a run reads the files once per concurrency targets rather than once per target. Without
+// run targets in ctx, read is called for domain alone.
+func readFiles(ctx context.Context, source, domain string, concurrency int, read func(ctx context.Context, route func(result.Result) bool), out chan<- result.Result) error {
+	s := &fileSubscriber{ctx: ctx, domain: domain, results: make(chan result.Result, 64)}
+	sr := &sharedRead{read: read, limit: 1, left: 1}
+	if run, ok := ctx.Value(runTargetsContextKey{}).(*runTargets); ok {
+		if _, isTarget := run.targets[domain]; isTarget {
+			run.mu.Lock()
+			if shared, ok := run.reads[source]; ok {
+				sr = shared
+			} else {
+				sr.left = run.count
+				if concurrency > 1 {
+					sr.limit = concurrency
+				}
+				run.reads[source] = sr
+			}
+			run.mu.Unlock()
+		}
+	}
+	sr.join(s)
+
+	for r := range s.results {
+		if !emit(ctx, out, r) {
+			// The pass stops sending once ctx is done; drain what it already sent.
+			for range s.results {
+			}
+			break
+		}
+	}
+	return ctx.Err()
+}
+
+// onDomain reports whether rawURL is on domain or one of its subdomains. Finer scoping is left to
+// the scope rules applied by the pipeline.
+func onDomain(rawURL, domain string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host, err := psl.Normalize(u.Hostname())
+	if err != nil {
+		return false
+	}
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
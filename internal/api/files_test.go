@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// TestReadFilesSharesPasses runs targets the way the pipeline does, fewer at a time than there are
+// targets, and checks that they share passes over the files and each receives only its own URLs.
+func TestReadFilesSharesPasses(t *testing.T) {
+	targets := []string{"a.example", "b.example", "c.example", "d.example", "e.example"}
+	urls := []string{
+		"https://a.example/?x=1",
+		"https://www.b.example/?x=2",
+		"https://c.example/?x=3",
+		"https://other.example/?x=4",
+		"https://e.example/?x=5",
+		"https://api.a.example/?x=6",
+	}
+	var passes int32
+	read := func(ctx context.Context, route func(result.Result) bool) {
+		atomic.AddInt32(&passes, 1)
+		for _, u := range urls {
+			if !route(result.Result{URL: u}) {
+				return
+			}
+		}
+	}
+
+	const concurrency = 2
+	ctx, cancel := context.WithTimeout(WithTargets(context.Background(), targets), 5*time.Second)
+	defer cancel()
+	got := make(map[string][]string)
+	var mu sync.Mutex
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(target string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			out := make(chan result.Result, len(urls))
+			if err := readFiles(withSource(ctx, "local"), "local", target, concurrency, read, out); err != nil {
+				t.Errorf("%s: readFiles: %v", target, err)
+			}
+			close(out)
+			mu.Lock()
+			defer mu.Unlock()
+			for r := range out {
+				got[target] = append(got[target], r.URL)
+			}
+		}(target)
+	}
+	wg.Wait()
+
+	want := map[string][]string{
+		"a.example": {"https://a.example/?x=1", "https://api.a.example/?x=6"},
+		"b.example": {"https://www.b.example/?x=2"},
+		"c.example": {"https://c.example/?x=3"},
+		"e.example": {"https://e.example/?x=5"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("URLs by target = %q, want %q", got, want)
+	}
+	// Five targets, two at a time: three passes.
+	if n := atomic.LoadInt32(&passes); n != 3 {
+		t.Errorf("files read %d times, want 3", n)
+	}
+}
+
+func TestReadFilesWithoutRunTargets(t *testing.T) {
+	read := func(ctx context.Context, route func(result.Result) bool) {
+		route(result.Result{URL: "https://example.com/?a=1"})
+		route(result.Result{URL: "https://example.org/?b=2"})
+	}
+	out := make(chan result.Result, 2)
+	if err := readFiles(context.Background(), "local", "example.com", 5, read, out); err != nil {
+		t.Fatal(err)
+	}
+	close(out)
+	var got []string
+	for r := range out {
+		got = append(got, r.URL)
+	}
+	if want := []string{"https://example.com/?a=1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("URLs = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/importer"
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+func init() {
+	Register(NewOptInSource("import", FetchImports))
+}
+
+// FetchImports reads the proxy history exports in cfg.ImportFiles (see package importer) and sends
+// the requests made to the domain or its subdomains to out (see fetchFiles), with parameters sent in
+// request bodies merged into their query strings.
+func FetchImports(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error {
+	files := expandFiles(cfg.ImportFiles)
+	if len(files) == 0 {
+		return fmt.Errorf("no import files configured (set import_files or use --import)")
+	}
+	return fetchFiles(ctx, "import", domain, files, "proxy history export", importer.ReadFile, cfg, out)
+}
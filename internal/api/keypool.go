@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/grumpzsux/goParams/internal/config"
+)
+
+// KeyAuth describes how a source sends a pooled API key: in a request header, a query parameter, or both.
+type KeyAuth struct {
+	Credential string // Configuration key of the credential, e.g. config.KeyVirusTotalAPIKey.
+	Header     string // Header carrying the key, if any.
+	Param      string // Query parameter carrying the key, if any.
+}
+
+const (
+	// keyRateLimitBench is how long a key is benched after a 429 response without Retry-After.
+	keyRateLimitBench = time.Minute
+	// keyForbiddenBench is how long a key is benched after a 403 quota response, which VirusTotal
+	// sends once a key's daily quota is used up.
+	keyForbiddenBench = time.Hour
+)
+
+// quotaErrorCode is the error code VirusTotal sends with a 403 or 429 response when a key's quota
+// is exhausted. Other 403 responses, such as a key without access to an endpoint, are not quota errors.
+const quotaErrorCode = "QuotaExceededError"
+
+// pooledKey is one API key of a pool.
+type pooledKey struct {
+	value        string
+	benchedUntil time.Time
+}
+
+// keyPool rotates requests round-robin across the keys configured for a credential.
+type keyPool struct {
+	mu   sync.Mutex
+	keys []*pooledKey
+	next int
+}
+
+// acquire returns the next key that is not benched. If every key is benched, keys are handed out
+// round-robin regardless so that the caller's retry and backoff logic takes over.
+func (p *keyPool) acquire() *pooledKey {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.keys) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for i := 0; i < len(p.keys); i++ {
+		k := p.keys[(p.next+i)%len(p.keys)]
+		if now.After(k.benchedUntil) {
+			p.next = (p.next + i + 1) % len(p.keys)
+			return k
+		}
+	}
+	k := p.keys[p.next]
+	p.next = (p.next + 1) % len(p.keys)
+	return k
+}
+
+// bench takes k out of rotation for d and reports whether another key is still available.
+func (p *keyPool) bench(k *pooledKey, d time.Duration) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	k.benchedUntil = now.Add(d)
+	for _, other := range p.keys {
+		if now.After(other.benchedUntil) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	keyPoolsMu sync.Mutex
+	keyPools   = make(map[string]*keyPool)
+)
+
+// keyPoolFor returns the pool for credential, built from cfg on first use.
+// Like the rate limiters, pools are shared by every request across all domains being processed.
+func keyPoolFor(cfg *config.Config, credential string) *keyPool {
+	keyPoolsMu.Lock()
+	defer keyPoolsMu.Unlock()
+	pool, ok := keyPools[credential]
+	if !ok {
+		pool = &keyPool{}
+		for _, value := range cfg.CredentialPool(credential) {
+			pool.keys = append(pool.keys, &pooledKey{value: value})
+		}
+		keyPools[credential] = pool
+	}
+	return pool
+}
+
+// quotaBench reports whether resp is a quota response that should bench its key, and for how long.
+// Every 429 is one; a 403 only if its body carries quotaErrorCode.
+func quotaBench(resp *http.Response) (time.Duration, bool) {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		if d, ok := retryAfter(resp); ok && d > 0 {
+			return d, true
+		}
+		return keyRateLimitBench, true
+	case http.StatusForbidden:
+		if providerErrorCode(resp) == quotaErrorCode {
+			return keyForbiddenBench, true
+		}
+	}
+	return 0, false
+}
+
+// providerErrorCode returns the code of a JSON error body of the form {"error": {"code": "..."}},
+// as sent by VirusTotal, or an empty string. The body stays readable from the start.
+func providerErrorCode(resp *http.Response) string {
+	peeked, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), resp.Body), resp.Body}
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(peeked, &body) != nil {
+		return ""
+	}
+	return body.Error.Code
+}
+
+// maskKey returns a form of key that is safe to log: its last four characters.
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return "..." + key[len(key)-4:]
+}
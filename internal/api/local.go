@@ -0,0 +1,24 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grumpzsux/goParams/internal/archive"
+	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+func init() {
+	Register(NewOptInSource("local", FetchLocal))
+}
+
+// FetchLocal reads the archive files in cfg.LocalFiles (see package archive) and sends the URLs
+// captured on the domain or its subdomains to out (see fetchFiles). No network requests are made.
+func FetchLocal(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error {
+	files := expandFiles(cfg.LocalFiles)
+	if len(files) == 0 {
+		return fmt.Errorf("no local files configured (set local_files or use 'goParams ingest')")
+	}
+	return fetchFiles(ctx, "local", domain, files, "archive file", archive.ReadFile, cfg, out)
+}
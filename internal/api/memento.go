@@ -0,0 +1,285 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+func init() {
+	Register(NewSource("memento", FetchMemento))
+}
+
+// DefaultMementoEndpoints are the TimeMap endpoints queried when memento_endpoints is not configured:
+// archive.today and the Memento aggregator, which fans out to many public archives.
+var DefaultMementoEndpoints = []string{
+	"https://archive.ph/timemap/",
+	"https://timetravel.mementoweb.org/timemap/link/",
+}
+
+// mementoEndpoints returns the configured TimeMap endpoints, or the defaults.
+func mementoEndpoints(cfg *config.Config) []string {
+	if len(cfg.MementoEndpoints) > 0 {
+		return cfg.MementoEndpoints
+	}
+	return DefaultMementoEndpoints
+}
+
+// mementoTimeMapURL returns the TimeMap URL for a URL pattern. An endpoint containing "{url}" has it
+// replaced by the pattern; otherwise the pattern is appended.
+func mementoTimeMapURL(endpoint, pattern string) string {
+	if strings.Contains(endpoint, "{url}") {
+		return strings.Replace(endpoint, "{url}", pattern, 1)
+	}
+	return endpoint + pattern
+}
+
+// mementoPattern returns the URL pattern to look up: every URL under the domain ("domain/*"), or under
+// the domain and its subdomains ("*.domain") when includeSubdomains is set. Archives that do not
+// support prefix or wildcard lookups return only the mementos of the pattern itself.
+func mementoPattern(domain string, includeSubdomains bool) string {
+	if includeSubdomains {
+		return "*." + domain
+	}
+	return domain + "/*"
+}
+
+// FetchMemento queries every configured Memento TimeMap endpoint for the domain and sends the original
+// URLs with query strings to out. Endpoints are queried concurrently; paged TimeMaps are followed
+// through their rel="next" links.
+func FetchMemento(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error {
+	pattern := mementoPattern(domain, cfg.IncludeSubdomains)
+	var wg sync.WaitGroup
+	for _, endpoint := range mementoEndpoints(cfg) {
+		wg.Add(1)
+		go func(endpoint string) {
+			defer wg.Done()
+			if err := walkMementoTimeMap(ctx, mementoTimeMapURL(endpoint, pattern), cfg, out); err != nil {
+				color.Yellow("Error fetching Memento TimeMap from %s: %v", endpoint, err)
+				reportError(ctx, fmt.Errorf("timemap %s: %w", endpoint, err))
+			}
+		}(endpoint)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// walkMementoTimeMap fetches a TimeMap and each following page of it.
+func walkMementoTimeMap(ctx context.Context, timeMapURL string, cfg *config.Config, out chan<- result.Result) error {
+	visited := make(map[string]struct{})
+	for timeMapURL != "" {
+		if _, dup := visited[timeMapURL]; dup {
+			return nil
+		}
+		visited[timeMapURL] = struct{}{}
+		pageURL := timeMapURL
+		next, err := fetchPage(ctx, out, pageURL, func(ctx context.Context) (string, error) {
+			return fetchMementoTimeMap(ctx, pageURL, cfg, out)
+		})
+		if err != nil {
+			return err
+		}
+		timeMapURL = next
+	}
+	return nil
+}
+
+// fetchMementoTimeMap requests a single link-format TimeMap and sends the original URLs of its
+// mementos to out. It returns the URL of the next TimeMap page, if any.
+func fetchMementoTimeMap(ctx context.Context, timeMapURL string, cfg *config.Config, out chan<- result.Result) (string, error) {
+	color.Blue("[*] Fetching Memento TimeMap: %s", timeMapURL)
+	resp, err := GetWithRandomUA(ctx, timeMapURL, cfg)
+	if err != nil {
+		return "", fmt.Errorf("error fetching TimeMap: %w", err)
+	}
+	defer resp.Body.Close()
+	// Archives answer 404 when they hold no mementos for the pattern.
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("TimeMap returned status code %d", resp.StatusCode)
+	}
+
+	next := ""
+	err = parseLinkFormat(bufio.NewReader(resp.Body), func(l link) bool {
+		rels := strings.Fields(l.params["rel"])
+		switch {
+		case hasRel(rels, "next") && (hasRel(rels, "timemap") || strings.Contains(l.params["type"], "link-format")):
+			next = l.uri
+		case hasRel(rels, "original") || hasRel(rels, "memento"):
+			original, ts := mementoOriginal(l, rels)
+			if original == "" || !strings.Contains(original, "?") {
+				return true
+			}
+			r := result.Result{URL: original}
+			r.SetSeen(ts)
+			return emit(ctx, out, r)
+		}
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("error parsing TimeMap: %w", err)
+	}
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	return next, nil
+}
+
+func hasRel(rels []string, rel string) bool {
+	for _, r := range rels {
+		if strings.EqualFold(r, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// mementoURLPattern matches the archived-URL form used by Wayback-style archives:
+// .../<timestamp><modifier>/<original URL>.
+var mementoURLPattern = regexp.MustCompile(`/(\d{4,14})(?:[a-z]{2}_)?/(https?:/+.+)$`)
+
+// mementoOriginal returns the original URL of a TimeMap entry and its capture time. Entries with
+// rel="original" carry it directly; memento URIs are unwrapped from the archive's URL form.
+func mementoOriginal(l link, rels []string) (string, time.Time) {
+	var ts time.Time
+	if dt := l.params["datetime"]; dt != "" {
+		ts, _ = http.ParseTime(dt)
+	}
+	if hasRel(rels, "original") {
+		return l.uri, ts
+	}
+	m := mementoURLPattern.FindStringSubmatch(l.uri)
+	if m == nil {
+		return "", ts
+	}
+	if ts.IsZero() {
+		ts = result.ParseTimestamp(m[1])
+	}
+	original := m[2]
+	// Some archives collapse the "//" after the scheme.
+	if i := strings.Index(original, ":/"); i >= 0 && !strings.HasPrefix(original[i:], "://") {
+		original = original[:i] + "://" + strings.TrimLeft(original[i+1:], "/")
+	}
+	return original, ts
+}
+
+// link is one entry of an RFC 6690 link-format document.
+type link struct {
+	uri    string
+	params map[string]string
+}
+
+// parseLinkFormat streams the entries of a link-format document (as used by Memento TimeMaps) to fn
+// until the input ends or fn returns false. Parameter names are lower-cased and quoted values unquoted.
+func parseLinkFormat(r *bufio.Reader, fn func(link) bool) error {
+	for {
+		// Skip separators up to the next "<".
+		if _, err := r.ReadString('<'); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		uri, err := r.ReadString('>')
+		if err != nil {
+			return fmt.Errorf("unterminated link target")
+		}
+		l := link{uri: strings.TrimSpace(strings.TrimSuffix(uri, ">")), params: make(map[string]string)}
+
+		// Parameters follow as ;name=value or ;name="quoted value" until a top-level comma.
+		done := false
+		for !done {
+			b, err := r.ReadByte()
+			if err == io.EOF {
+				fn(l)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			switch b {
+			case ',':
+				done = true
+			case ';':
+				name, value, last, err := readLinkParam(r)
+				if err != nil && err != io.EOF {
+					return err
+				}
+				if name != "" {
+					l.params[strings.ToLower(name)] = value
+				}
+				if err == io.EOF {
+					fn(l)
+					return nil
+				}
+				done = last
+			}
+		}
+		if !fn(l) {
+			return nil
+		}
+	}
+}
+
+// readLinkParam reads one link parameter after its ";". It reports whether the parameter ended the
+// link (a comma followed it).
+func readLinkParam(r *bufio.Reader) (name, value string, last bool, err error) {
+	var sb strings.Builder
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return strings.TrimSpace(sb.String()), "", false, err
+		}
+		switch b {
+		case '=':
+			name = strings.TrimSpace(sb.String())
+			value, last, err = readLinkValue(r)
+			return name, value, last, err
+		case ';':
+			r.UnreadByte()
+			return strings.TrimSpace(sb.String()), "", false, nil
+		case ',':
+			return strings.TrimSpace(sb.String()), "", true, nil
+		default:
+			sb.WriteByte(b)
+		}
+	}
+}
+
+// readLinkValue reads a parameter value, quoted or not, stopping before the next ";" or after a ",".
+func readLinkValue(r *bufio.Reader) (string, bool, error) {
+	var sb strings.Builder
+	quoted := false
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return strings.TrimSpace(sb.String()), false, err
+		}
+		switch {
+		case b == '"':
+			quoted = !quoted
+		case b == '\\' && quoted:
+			if esc, err := r.ReadByte(); err == nil {
+				sb.WriteByte(esc)
+			}
+		case !quoted && b == ';':
+			r.UnreadByte()
+			return strings.TrimSpace(sb.String()), false, nil
+		case !quoted && b == ',':
+			return strings.TrimSpace(sb.String()), true, nil
+		default:
+			sb.WriteByte(b)
+		}
+	}
+}
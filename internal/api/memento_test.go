@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// timeMapPage1 is the first page of a paged TimeMap in the form archives serve it: the original
+// resource with several rel values, quoted attributes containing commas and semicolons, a link to the
+// next page, and mementos in the archive's URL form.
+const timeMapPage1 = `<https://example.com/search?q=1>; rel="original",
+<https://archive.example/timemap/link/https://example.com/search?q=1>; rel="self"; type="application/link-format"; from="Mon, 01 Jan 2018 10:00:00 GMT"; until="Tue, 02 Jan 2018 10:00:00 GMT",
+<https://archive.example/timemap/link/2/https://example.com/search?q=1>; rel="timemap next"; type="application/link-format"; from="Wed, 03 Jan 2018 10:00:00 GMT",
+<https://archive.example/timegate/https://example.com/search?q=1>; rel="timegate",
+<https://archive.example/web/20180101100000/https://example.com/search?q=1>; rel="first memento"; datetime="Mon, 01 Jan 2018 10:00:00 GMT"; title="Search, results; page 1",
+<https://archive.example/web/20180102100000id_/http:/example.com/item?id=7>; rel="memento"; datetime="Tue, 02 Jan 2018 10:00:00 GMT",
+<https://archive.example/web/20180102110000/https://example.com/about>; rel="memento"; datetime="Tue, 02 Jan 2018 11:00:00 GMT"
+`
+
+const timeMapPage2 = `<https://example.com/search?q=1>; rel="original",
+<https://archive.example/web/20180103100000/https://example.com/search?q=2&lang=en>; rel="last memento"; datetime="Wed, 03 Jan 2018 10:00:00 GMT"
+`
+
+func TestParseLinkFormat(t *testing.T) {
+	var links []link
+	err := parseLinkFormat(bufio.NewReader(strings.NewReader(timeMapPage1)), func(l link) bool {
+		links = append(links, l)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 7 {
+		t.Fatalf("parsed %d links, want 7: %+v", len(links), links)
+	}
+	self := links[1]
+	if self.uri != "https://archive.example/timemap/link/https://example.com/search?q=1" {
+		t.Errorf("self link = %q", self.uri)
+	}
+	wantSelf := map[string]string{
+		"rel":   "self",
+		"type":  "application/link-format",
+		"from":  "Mon, 01 Jan 2018 10:00:00 GMT",
+		"until": "Tue, 02 Jan 2018 10:00:00 GMT",
+	}
+	if !reflect.DeepEqual(self.params, wantSelf) {
+		t.Errorf("self params = %q, want %q", self.params, wantSelf)
+	}
+	if got := links[2].params["rel"]; got != "timemap next" {
+		t.Errorf("next rel = %q, want %q", got, "timemap next")
+	}
+	first := links[4]
+	if got := first.params["title"]; got != "Search, results; page 1" {
+		t.Errorf("quoted title = %q", got)
+	}
+	if got := first.params["datetime"]; got != "Mon, 01 Jan 2018 10:00:00 GMT" {
+		t.Errorf("datetime after quoted commas = %q", got)
+	}
+}
+
+func TestFetchMementoTimeMapPaging(t *testing.T) {
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		w.Header().Set("Content-Type", "application/link-format")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/timemap/link/2/"):
+			fmt.Fprint(w, timeMapPage2)
+		case strings.HasPrefix(r.URL.Path, "/timemap/link/"):
+			fmt.Fprint(w, timeMapPage1)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{NoCache: true}
+	if err := config.Validate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	old := HTTPClient.Transport
+	HTTPClient.Transport = rewriteTransport{target: srv.Listener.Addr().String()}
+	defer func() { HTTPClient.Transport = old }()
+
+	out := make(chan result.Result, 100)
+	ctx := withSource(context.Background(), "memento")
+	if err := walkMementoTimeMap(ctx, "https://archive.example/timemap/link/https://example.com/search?q=1", cfg, out); err != nil {
+		t.Fatalf("walkMementoTimeMap: %v", err)
+	}
+	close(out)
+
+	if len(requested) != 2 {
+		t.Errorf("requested %q, want the first page and the rel=\"timemap next\" page", requested)
+	}
+	got := make(map[string]result.Result)
+	for r := range out {
+		if prev, ok := got[r.URL]; ok {
+			prev.Merge(r)
+			r = prev
+		}
+		got[r.URL] = r
+	}
+	want := []string{
+		"http://example.com/item?id=7",
+		"https://example.com/search?q=1",
+		"https://example.com/search?q=2&lang=en",
+	}
+	if sorted := resultURLs(mapResults(got)); !reflect.DeepEqual(sorted, want) {
+		t.Errorf("URLs = %q, want %q", sorted, want)
+	}
+	item := got["http://example.com/item?id=7"]
+	if wantSeen := time.Date(2018, 1, 2, 10, 0, 0, 0, time.UTC); item.LastSeen == nil || !item.LastSeen.Equal(wantSeen) {
+		t.Errorf("item last seen = %v, want %v", item.LastSeen, wantSeen)
+	}
+}
+
+func mapResults(m map[string]result.Result) []result.Result {
+	var results []result.Result
+	for _, r := range m {
+		results = append(results, r)
+	}
+	return results
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grumpzsux/goParams/internal/config"
+)
+
+// tokenBucket is a simple token-bucket rate limiter.
+// Tokens are refilled continuously at rate per second up to burst.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a limiter allowing perMinute requests per minute with a burst of one.
+func newTokenBucket(perMinute int) *tokenBucket {
+	return &tokenBucket{
+		rate:   float64(perMinute) / 60.0,
+		burst:  1,
+		tokens: 1,
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or the context is cancelled.
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*tokenBucket)
+)
+
+// waitForRateLimit blocks until the source associated with ctx may issue another request.
+// Requests made with a pooled API key are limited per key, so that every key of a pool gets the
+// source's full rate; other requests share one limiter per source. Limiters are shared by every
+// request across all domains being processed.
+func waitForRateLimit(ctx context.Context, cfg *config.Config, key string) error {
+	source := sourceFromContext(ctx)
+	perMinute := cfg.RateLimitFor(source)
+	if perMinute <= 0 {
+		return nil
+	}
+	name := source
+	if key != "" {
+		name = source + "\x00" + key
+	}
+
+	limitersMu.Lock()
+	limiter, ok := limiters[name]
+	if !ok {
+		limiter = newTokenBucket(perMinute)
+		limiters[name] = limiter
+	}
+	limitersMu.Unlock()
+
+	return limiter.Wait(ctx)
+}
+
+type sourceContextKey struct{}
+
+// withSource returns a context that records which source is issuing requests.
+func withSource(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, sourceContextKey{}, name)
+}
+
+// sourceFromContext returns the source name recorded by withSource, or an empty string.
+func sourceFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(sourceContextKey{}).(string)
+	return name
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grumpzsux/goParams/internal/config"
+)
+
+// rewriteTransport sends every request to target, whatever its URL.
+type rewriteTransport struct{ target string }
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = "http", t.target
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestWaitForRateLimitPerKey(t *testing.T) {
+	cfg := &config.Config{RateLimits: map[string]int{"ratelimit-per-key": 60}}
+	ctx := withSource(context.Background(), "ratelimit-per-key")
+
+	// Each key starts with its own token, so one request per key goes through at once.
+	for _, key := range []string{"key-one", "key-two", "key-three"} {
+		start := time.Now()
+		if err := waitForRateLimit(ctx, cfg, key); err != nil {
+			t.Fatalf("key %s: %v", key, err)
+		}
+		if d := time.Since(start); d > 100*time.Millisecond {
+			t.Errorf("first request with %s waited %s, want no wait", key, d)
+		}
+	}
+
+	// A second request with the same key waits for the key's next token (one a second).
+	short, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	if err := waitForRateLimit(short, cfg, "key-one"); err == nil {
+		t.Error("second request with key-one did not wait for its rate limit")
+	}
+}
+
+func TestWaitForRateLimitWithoutKey(t *testing.T) {
+	cfg := &config.Config{RateLimits: map[string]int{"ratelimit-source": 60}}
+	ctx := withSource(context.Background(), "ratelimit-source")
+	if err := waitForRateLimit(ctx, cfg, ""); err != nil {
+		t.Fatal(err)
+	}
+	short, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	if err := waitForRateLimit(short, cfg, ""); err == nil {
+		t.Error("second request without a key did not wait for the source's rate limit")
+	}
+}
+
+// TestGetWithAPIKeyPoolThroughput checks that a pool of keys multiplies the source's rate: with
+// three keys at 60 requests a minute, three requests are made at once, one with each key.
+func TestGetWithAPIKeyPoolThroughput(t *testing.T) {
+	var mu sync.Mutex
+	perKey := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		perKey[r.Header.Get("x-apikey")]++
+		mu.Unlock()
+		fmt.Fprint(w, "{}")
+	}))
+	defer srv.Close()
+	old := HTTPClient.Transport
+	HTTPClient.Transport = rewriteTransport{target: srv.Listener.Addr().String()}
+	defer func() { HTTPClient.Transport = old }()
+
+	cfg := &config.Config{
+		VirusTotalAPIKeys: []string{"pool-key-aaaa1", "pool-key-aaaa2", "pool-key-aaaa3"},
+		RateLimits:        map[string]int{"ratelimit-pool": 60},
+		NoCache:           true,
+	}
+	if err := config.Validate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(withSource(context.Background(), "ratelimit-pool"), 500*time.Millisecond)
+	defer cancel()
+	auth := KeyAuth{Credential: config.KeyVirusTotalAPIKey, Header: "x-apikey"}
+	for i := 0; i < 3; i++ {
+		resp, err := GetWithAPIKey(ctx, "https://example.invalid/", cfg, auth, nil)
+		if err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+		discardBody(resp)
+	}
+	for _, key := range cfg.VirusTotalAPIKeys {
+		if perKey[key] != 1 {
+			t.Errorf("key %s made %d requests, want 1 (requests per key: %v)", key, perKey[key], perKey)
+		}
+	}
+}
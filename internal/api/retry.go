@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// baseBackoff is the upper bound of the first retry delay; it doubles with every attempt.
+	baseBackoff = 1 * time.Second
+	// maxBackoff caps both the computed delay and any Retry-After value sent by a provider.
+	maxBackoff = 2 * time.Minute
+)
+
+// isRetryableStatus reports whether a response status indicates a transient failure.
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// backoffDelay returns a jittered exponential delay for the given attempt (starting at 1).
+// It uses "full jitter": a random duration between zero and base*2^(attempt-1).
+func backoffDelay(attempt int) time.Duration {
+	ceiling := baseBackoff << uint(attempt-1)
+	if ceiling <= 0 || ceiling > maxBackoff {
+		ceiling = maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)) + 1)
+}
+
+// retryAfter parses the Retry-After header, which may hold either delay seconds or an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	var delay time.Duration
+	if secs, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(secs) * time.Second
+	} else if when, err := http.ParseTime(value); err == nil {
+		delay = time.Until(when)
+	} else {
+		return 0, false
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay, true
+}
+
+// sleepContext waits for d or until the context is cancelled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// discardBody drains and closes a response body so the connection can be reused.
+func discardBody(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
@@ -0,0 +1,28 @@
+package api
+
+import "context"
+
+// Scope decides which URLs belong to the target being harvested. It is implemented by scope.Scope.
+type Scope interface {
+	// InScope reports whether rawURL is in scope for target.
+	InScope(rawURL, target string) bool
+}
+
+type scopeContextKey struct{}
+
+// WithScope returns a context whose sources can check URLs against s before following them. The
+// pipeline filters every emitted URL itself; sources that crawl, such as sitemap, use it to decide
+// which documents to fetch.
+func WithScope(ctx context.Context, s Scope) context.Context {
+	return context.WithValue(ctx, scopeContextKey{}, s)
+}
+
+// inScope reports whether rawURL is in scope for the target recorded in ctx. Without a scope in ctx,
+// URLs on the target and its subdomains are in scope.
+func inScope(ctx context.Context, rawURL string) bool {
+	target := targetFromContext(ctx)
+	if s, ok := ctx.Value(scopeContextKey{}).(Scope); ok {
+		return s.InScope(rawURL, target)
+	}
+	return onDomain(rawURL, target)
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+func init() {
+	Register(NewOptInSource("sitemap", FetchSitemap))
+}
+
+// maxSitemaps bounds the number of sitemaps fetched per target, since sitemap indexes can fan out
+// to thousands of files on large sites.
+const maxSitemaps = 500
+
+// FetchSitemap contacts the target itself: it reads robots.txt, sends the parameterized Allow and
+// Disallow paths to out, and follows its Sitemap directives (or /sitemap.xml if there are none)
+// through sitemap indexes and gzipped sitemaps, sending the <loc> URLs with query strings to out.
+// Only sitemaps in scope are fetched. The source is opt-in because, unlike the others, it sends
+// requests to the target.
+func FetchSitemap(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error {
+	base, sitemaps, err := fetchRobots(ctx, domain, cfg, out)
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if len(sitemaps) == 0 {
+		sitemaps = []string{base + "/sitemap.xml"}
+	}
+
+	visited := make(map[string]struct{})
+	queue := sitemaps
+	for len(queue) > 0 && len(visited) < maxSitemaps {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+		if _, dup := visited[sitemapURL]; dup || !inScope(ctx, sitemapURL) {
+			continue
+		}
+		visited[sitemapURL] = struct{}{}
+		children, err := fetchSitemap(ctx, sitemapURL, cfg, out)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			color.Yellow("Error fetching sitemap %s: %v", sitemapURL, err)
+			reportError(ctx, fmt.Errorf("sitemap %s: %w", sitemapURL, err))
+			continue
+		}
+		queue = append(queue, children...)
+	}
+	if len(visited) >= maxSitemaps && len(queue) > 0 {
+		color.Yellow("Stopped after %d sitemaps for %s; %d more were not fetched", maxSitemaps, domain, len(queue))
+	}
+	return nil
+}
+
+// fetchRobots fetches robots.txt for domain over HTTPS, falling back to HTTP if the connection
+// fails. It sends the parameterized Allow and Disallow paths to out and returns the base URL that
+// answered along with the sitemaps the file lists. A missing robots.txt is not an error.
+func fetchRobots(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) (string, []string, error) {
+	var resp *http.Response
+	var base string
+	var err error
+	for _, scheme := range []string{"https", "http"} {
+		base = scheme + "://" + domain
+		color.Blue("[*] Fetching robots.txt: %s/robots.txt", base)
+		resp, err = GetWithRandomUA(ctx, base+"/robots.txt", cfg)
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("error fetching robots.txt: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return base, nil, nil
+	}
+
+	robotsURL, _ := url.Parse(base + "/robots.txt")
+	var sitemaps []string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "sitemap":
+			if ref, err := url.Parse(value); err == nil && value != "" {
+				sitemaps = append(sitemaps, robotsURL.ResolveReference(ref).String())
+			}
+		case "allow", "disallow":
+			if !strings.Contains(value, "?") || !strings.HasPrefix(value, "/") {
+				continue
+			}
+			// Wildcards and end anchors are robots.txt syntax, not part of the path.
+			path := strings.TrimSuffix(strings.ReplaceAll(value, "*", ""), "$")
+			if !emit(ctx, out, result.Result{URL: base + path}) {
+				return base, sitemaps, nil
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return base, sitemaps, fmt.Errorf("error reading robots.txt: %w", err)
+	}
+	return base, sitemaps, nil
+}
+
+// fetchSitemap fetches one sitemap, sends its parameterized page URLs to out and returns the child
+// sitemaps it lists if it is a sitemap index. XML sitemaps, plain-text sitemaps with one URL per
+// line, and gzipped variants of either are accepted. A missing sitemap is not an error.
+func fetchSitemap(ctx context.Context, sitemapURL string, cfg *config.Config, out chan<- result.Result) ([]string, error) {
+	color.Blue("[*] Fetching sitemap: %s", sitemapURL)
+	resp, err := GetWithRandomUA(ctx, sitemapURL, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching sitemap: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sitemap returned status code %d", resp.StatusCode)
+	}
+
+	body := bufio.NewReader(resp.Body)
+	// Gzipped sitemaps are recognised by their content, since servers label them inconsistently.
+	if magic, _ := body.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing sitemap: %w", err)
+		}
+		defer gz.Close()
+		body = bufio.NewReader(gz)
+	}
+	page := func(loc string) bool {
+		if !strings.Contains(loc, "?") {
+			return true
+		}
+		return emit(ctx, out, result.Result{URL: loc})
+	}
+	var children []string
+	if isXML(body) {
+		err = parseSitemapXML(body, page, func(loc string) { children = append(children, loc) })
+	} else {
+		err = parseSitemapText(body, page)
+	}
+	if err != nil {
+		return children, fmt.Errorf("error parsing sitemap: %w", err)
+	}
+	discardBody(resp)
+	return children, nil
+}
+
+// isXML reports whether the first non-space byte of r, after any byte order mark, is "<".
+func isXML(r *bufio.Reader) bool {
+	head, _ := r.Peek(512)
+	head = bytes.TrimLeft(bytes.TrimPrefix(bytes.TrimLeft(head, " \t\r\n"), []byte("\ufeff")), " \t\r\n")
+	return bytes.HasPrefix(head, []byte("<"))
+}
+
+// parseSitemapXML streams the <loc> entries of a urlset to page, and those of a sitemapindex to
+// child, until the document ends or page returns false. Other <loc> elements, such as image
+// extensions inside <url>, are ignored.
+func parseSitemapXML(r io.Reader, page func(loc string) bool, child func(loc string)) error {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	var stack []string
+	var loc strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			if t.Name.Local == "loc" {
+				loc.Reset()
+			}
+		case xml.CharData:
+			if len(stack) > 0 && stack[len(stack)-1] == "loc" {
+				loc.Write(t)
+			}
+		case xml.EndElement:
+			if len(stack) >= 2 && t.Name.Local == "loc" {
+				value := strings.TrimSpace(loc.String())
+				switch stack[len(stack)-2] {
+				case "url":
+					if value != "" && !page(value) {
+						return nil
+					}
+				case "sitemap":
+					if value != "" {
+						child(value)
+					}
+				}
+			}
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+}
+
+// parseSitemapText streams the URLs of a plain-text sitemap to page.
+func parseSitemapText(r io.Reader, page func(loc string) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+			if !page(line) {
+				return nil
+			}
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// Source is a data provider that goParams can harvest URLs from.
+// Providers register themselves with Register, usually from an init function.
+type Source interface {
+	// Name returns the unique, lower-case identifier used to select the source.
+	Name() string
+	// RequiredCredentials returns the configuration keys the source needs in order to run.
+	RequiredCredentials() []string
+	// Fetch retrieves URLs with query parameters for the given domain and sends them to out as they arrive,
+	// along with whatever capture metadata the provider reports.
+	// Implementations must not close out and should stop early when ctx is cancelled.
+	Fetch(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error
+}
+
+// OptInSource is implemented by sources that only run when selected by name, such as sources that
+// read local files or contact the targets themselves rather than querying a passive provider.
+type OptInSource interface {
+	Source
+	// OptIn reports whether the source is left out of the default selection.
+	OptIn() bool
+}
+
+// IsOptIn reports whether s only runs when selected by name.
+func IsOptIn(s Source) bool {
+	o, ok := s.(OptInSource)
+	return ok && o.OptIn()
+}
+
+// funcSource adapts a FetchFunc to the Source interface.
+type funcSource struct {
+	name        string
+	credentials []string
+	fetch       FetchFunc
+	optIn       bool
+}
+
+func (s *funcSource) Name() string                  { return s.name }
+func (s *funcSource) RequiredCredentials() []string { return s.credentials }
+func (s *funcSource) OptIn() bool                   { return s.optIn }
+
+func (s *funcSource) Fetch(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error {
+	return s.fetch(ctx, domain, cfg, out)
+}
+
+// NewSource wraps a FetchFunc as a Source with the given name and required credentials.
+func NewSource(name string, fetch FetchFunc, credentials ...string) Source {
+	return &funcSource{name: name, credentials: credentials, fetch: fetch}
+}
+
+// NewOptInSource is like NewSource, but the source only runs when selected by name (see OptInSource).
+func NewOptInSource(name string, fetch FetchFunc, credentials ...string) Source {
+	return &funcSource{name: name, credentials: credentials, fetch: fetch, optIn: true}
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Source)
+)
+
+// Register makes a source available to FetchAll and the CLI.
+// It panics if a source with the same name is already registered.
+func Register(s Source) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	name := strings.ToLower(s.Name())
+	if _, dup := registry[name]; dup {
+		panic("api: Register called twice for source " + name)
+	}
+	registry[name] = s
+}
+
+// Sources returns all registered sources sorted by name.
+func Sources() []Source {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	sources := make([]Source, 0, len(registry))
+	for _, s := range registry {
+		sources = append(sources, s)
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Name() < sources[j].Name() })
+	return sources
+}
+
+// LookupSource returns the registered source with the given name.
+func LookupSource(name string) (Source, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	s, ok := registry[strings.ToLower(strings.TrimSpace(name))]
+	return s, ok
+}
+
+// SelectSources returns the registered sources named in include (or all sources except opt-in ones if
+// include is empty), minus any named in exclude. Unknown names are reported as an error.
+func SelectSources(include, exclude []string) ([]Source, error) {
+	excluded := make(map[string]struct{})
+	for _, name := range exclude {
+		s, ok := LookupSource(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown source %q", name)
+		}
+		excluded[s.Name()] = struct{}{}
+	}
+
+	var candidates []Source
+	if len(include) == 0 {
+		for _, s := range Sources() {
+			if !IsOptIn(s) {
+				candidates = append(candidates, s)
+			}
+		}
+	} else {
+		seen := make(map[string]struct{})
+		for _, name := range include {
+			s, ok := LookupSource(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown source %q", name)
+			}
+			if _, dup := seen[s.Name()]; dup {
+				continue
+			}
+			seen[s.Name()] = struct{}{}
+			candidates = append(candidates, s)
+		}
+	}
+
+	var selected []Source
+	for _, s := range candidates {
+		if _, skip := excluded[s.Name()]; !skip {
+			selected = append(selected, s)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no sources selected")
+	}
+	return selected, nil
+}
+
+// MissingCredentials returns the credentials required by s that are not set in cfg.
+func MissingCredentials(s Source, cfg *config.Config) []string {
+	var missing []string
+	for _, key := range s.RequiredCredentials() {
+		if cfg.Credential(key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// DisabledSource is a selected source that cannot run because credentials are missing.
+type DisabledSource struct {
+	Source  Source
+	Missing []string
+}
+
+// EnabledSources returns the sources selected by cfg.Sources and cfg.ExcludeSources whose
+// credentials are all configured, together with the selected sources that had to be disabled.
+// It is an error if no selected source can run.
+func EnabledSources(cfg *config.Config) ([]Source, []DisabledSource, error) {
+	selected, err := SelectSources(cfg.Sources, cfg.ExcludeSources)
+	if err != nil {
+		return nil, nil, err
+	}
+	var enabled []Source
+	var disabled []DisabledSource
+	for _, s := range selected {
+		if missing := MissingCredentials(s, cfg); len(missing) > 0 {
+			disabled = append(disabled, DisabledSource{Source: s, Missing: missing})
+			continue
+		}
+		enabled = append(enabled, s)
+	}
+	if len(enabled) == 0 {
+		return nil, disabled, fmt.Errorf("no usable sources: every selected source is missing credentials")
+	}
+	return enabled, disabled, nil
+}
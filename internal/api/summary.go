@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SourceSummary holds the outcome of a single source across a run.
+type SourceSummary struct {
+	Name   string
+	URLs   int      // Raw URLs emitted, before cleaning and deduplication.
+	Errors []string // Failures that caused results to be dropped.
+}
+
+// KeyUsage counts the requests made with one pooled API key across a run.
+type KeyUsage struct {
+	Credential string // Configuration key of the credential, e.g. "virustotal_api_key".
+	Key        string // The key, masked to its last four characters.
+	Requests   int
+	QuotaHits  int // 429 and 403 responses that benched the key.
+}
+
+// Summary collects per-source URL counts and errors, and per-key usage, for a run. It is safe for concurrent use.
+type Summary struct {
+	mu      sync.Mutex
+	sources map[string]*SourceSummary
+	keys    map[string]*KeyUsage
+}
+
+// NewSummary returns an empty Summary.
+func NewSummary() *Summary {
+	return &Summary{sources: make(map[string]*SourceSummary), keys: make(map[string]*KeyUsage)}
+}
+
+// get returns the entry for name, creating it if needed. The caller must hold s.mu.
+func (s *Summary) get(name string) *SourceSummary {
+	entry, ok := s.sources[name]
+	if !ok {
+		entry = &SourceSummary{Name: name}
+		s.sources[name] = entry
+	}
+	return entry
+}
+
+func (s *Summary) addURL(name string) {
+	s.mu.Lock()
+	s.get(name).URLs++
+	s.mu.Unlock()
+}
+
+func (s *Summary) addError(name string, err error) {
+	s.mu.Lock()
+	entry := s.get(name)
+	entry.Errors = append(entry.Errors, err.Error())
+	s.mu.Unlock()
+}
+
+func (s *Summary) addKeyUse(credential, key string, quota bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	masked := maskKey(key)
+	id := credential + "\x00" + key
+	entry, ok := s.keys[id]
+	if !ok {
+		entry = &KeyUsage{Credential: credential, Key: masked}
+		s.keys[id] = entry
+	}
+	entry.Requests++
+	if quota {
+		entry.QuotaHits++
+	}
+}
+
+// Keys returns a snapshot of the usage of every pooled API key, sorted by credential and key.
+func (s *Summary) Keys() []KeyUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]KeyUsage, 0, len(s.keys))
+	for _, entry := range s.keys {
+		list = append(list, *entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Credential != list[j].Credential {
+			return list[i].Credential < list[j].Credential
+		}
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// Sources returns a snapshot of every source's summary sorted by name.
+func (s *Summary) Sources() []SourceSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]SourceSummary, 0, len(s.sources))
+	for _, entry := range s.sources {
+		copied := *entry
+		copied.Errors = append([]string(nil), entry.Errors...)
+		list = append(list, copied)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// ErrorCount returns the number of errors recorded across all sources.
+func (s *Summary) ErrorCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, entry := range s.sources {
+		n += len(entry.Errors)
+	}
+	return n
+}
+
+// Log writes the summary through logrus, one line per source followed by its errors, then one
+// line per pooled API key.
+func (s *Summary) Log() {
+	for _, entry := range s.Sources() {
+		logrus.Infof("Source %s: %d URLs, %d errors", entry.Name, entry.URLs, len(entry.Errors))
+		for _, msg := range entry.Errors {
+			logrus.Warnf("  %s: %s", entry.Name, msg)
+		}
+	}
+	for _, entry := range s.Keys() {
+		logrus.Infof("Key %s %s: %d requests, %d quota responses", entry.Credential, entry.Key, entry.Requests, entry.QuotaHits)
+	}
+}
+
+type summaryContextKey struct{}
+
+// WithSummary returns a context whose requests record their outcome in s.
+func WithSummary(ctx context.Context, s *Summary) context.Context {
+	return context.WithValue(ctx, summaryContextKey{}, s)
+}
+
+// summaryFromContext returns the Summary attached by WithSummary, or nil.
+func summaryFromContext(ctx context.Context) *Summary {
+	s, _ := ctx.Value(summaryContextKey{}).(*Summary)
+	return s
+}
+
+// recordKeyUse counts a request made with a pooled API key in the summary attached to ctx.
+func recordKeyUse(ctx context.Context, credential, key string, quota bool) {
+	if s := summaryFromContext(ctx); s != nil {
+		s.addKeyUse(credential, key, quota)
+	}
+}
+
+// reportError records a failure for the source associated with ctx.
+func reportError(ctx context.Context, err error) {
+	if s := summaryFromContext(ctx); s != nil {
+		s.addError(sourceFromContext(ctx), err)
+	}
+}
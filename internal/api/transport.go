@@ -0,0 +1,120 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/grumpzsux/goParams/internal/config"
+)
+
+// Connection pool defaults used when the configuration leaves them unset.
+const (
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 10
+)
+
+// ConfigureHTTP replaces the transport of HTTPClient according to cfg: proxies (rotated round-robin
+// across requests), extra CA certificates, TLS verification and connection pool sizes. Without
+// configured proxies the standard HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment variables apply.
+// Request timeouts are chosen per source with cfg.TimeoutFor.
+func ConfigureHTTP(cfg *config.Config) error {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	proxies, err := cfg.ProxyURLs()
+	if err != nil {
+		return err
+	}
+	if len(proxies) > 0 {
+		rotator, err := newProxyRotator(proxies)
+		if err != nil {
+			return err
+		}
+		transport.Proxy = rotator.next
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CABundle != "" {
+		pool, err := loadCABundle(cfg.CABundle)
+		if err != nil {
+			return err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	transport.MaxIdleConns = defaultMaxIdleConns
+	if cfg.MaxIdleConns > 0 {
+		transport.MaxIdleConns = cfg.MaxIdleConns
+	}
+	transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	}
+
+	HTTPClient.Transport = transport
+	HTTPClient.Timeout = cfg.TimeoutFor("")
+	return nil
+}
+
+// proxyRotator hands out proxies round-robin, one per request.
+type proxyRotator struct {
+	proxies []*url.URL
+	counter uint64
+}
+
+// newProxyRotator parses the proxy URLs, which must use the http, https, socks5 or socks5h scheme.
+func newProxyRotator(rawProxies []string) (*proxyRotator, error) {
+	r := &proxyRotator{}
+	for _, raw := range rawProxies {
+		raw = strings.TrimSpace(raw)
+		if !strings.Contains(raw, "://") {
+			raw = "http://" + raw
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %w", raw, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("invalid proxy %q: unsupported scheme %q", raw, u.Scheme)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %q: missing host", raw)
+		}
+		r.proxies = append(r.proxies, u)
+	}
+	return r, nil
+}
+
+// next returns the proxy for a request; it has the signature of http.Transport.Proxy.
+func (r *proxyRotator) next(*http.Request) (*url.URL, error) {
+	n := atomic.AddUint64(&r.counter, 1) - 1
+	return r.proxies[n%uint64(len(r.proxies))], nil
+}
+
+// loadCABundle returns the system certificate pool extended with the PEM certificates in path.
+func loadCABundle(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ca_bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("ca_bundle: no PEM certificates found in " + path)
+	}
+	return pool, nil
+}
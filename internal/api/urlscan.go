@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+func init() {
+	Register(NewSource("urlscan", FetchURLScan))
+}
+
+// URLScanSearchURL is the urlscan.io search API endpoint. It is a variable so that tests can point it
+// at a stand-in server.
+var URLScanSearchURL = "https://urlscan.io/api/v1/search/"
+
+// urlScanPageSize is the number of results requested per search page.
+const urlScanPageSize = 100
+
+// urlScanAuth sends pooled urlscan.io keys, when configured, in the API-Key header.
+var urlScanAuth = KeyAuth{Credential: config.KeyURLScanAPIKey, Header: "API-Key"}
+
+// urlScanResponse is one page of urlscan.io search results.
+type urlScanResponse struct {
+	Results []struct {
+		Task struct {
+			URL  string `json:"url"`
+			Time string `json:"time"`
+		} `json:"task"`
+		Page struct {
+			URL      string `json:"url"`
+			Status   string `json:"status"`
+			MIMEType string `json:"mimeType"`
+		} `json:"page"`
+		Sort []json.RawMessage `json:"sort"`
+	} `json:"results"`
+	HasMore bool `json:"has_more"`
+}
+
+// urlScanEscape escapes the characters that are reserved in urlscan.io's query syntax.
+func urlScanEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`+-=&|><!(){}[]^"~*?:\/`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// urlScanQuery builds the search query for a domain: scans whose final page is on the domain (or,
+// with includeSubdomains, on any of its subdomains), plus scans whose submitted URL mentions it.
+// Hosts that are out of scope are dropped downstream.
+func urlScanQuery(domain string, includeSubdomains bool) string {
+	d := urlScanEscape(domain)
+	q := "page.domain:" + d
+	if includeSubdomains {
+		q += " OR page.domain:*." + d
+	}
+	return q + " OR task.url:*" + d + "*"
+}
+
+// FetchURLScan searches urlscan.io for scans of the given domain and sends the page and task URLs that
+// carry query strings to out. Pages are walked with search_after cursor pagination. The API key is
+// optional; without one, the public search limits apply.
+func FetchURLScan(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error {
+	q := url.Values{}
+	q.Set("q", urlScanQuery(domain, cfg.IncludeSubdomains))
+	q.Set("size", strconv.Itoa(urlScanPageSize))
+	color.Blue("[*] Searching urlscan.io for domain: %s", domain)
+
+	cursor := ""
+	for {
+		pq := cloneValues(q)
+		if cursor != "" {
+			pq.Set("search_after", cursor)
+		}
+		pageURL := URLScanSearchURL + "?" + pq.Encode()
+		next, err := fetchPage(ctx, out, pageURL, func(ctx context.Context) (string, error) {
+			return fetchURLScanPage(ctx, pageURL, cfg, out)
+		})
+		if err != nil || next == "" {
+			return err
+		}
+		cursor = next
+	}
+}
+
+// fetchURLScanPage requests a single search page, sends its URLs to out and returns the search_after
+// cursor of the following page, or an empty string on the last page.
+func fetchURLScanPage(ctx context.Context, pageURL string, cfg *config.Config, out chan<- result.Result) (string, error) {
+	var resp *http.Response
+	var err error
+	if cfg.Credential(config.KeyURLScanAPIKey) != "" {
+		resp, err = GetWithAPIKey(ctx, pageURL, cfg, urlScanAuth, nil)
+	} else {
+		resp, err = GetWithRandomUA(ctx, pageURL, cfg)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error fetching from urlscan.io: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		discardBody(resp)
+		return "", fmt.Errorf("urlscan.io returned status code %d", resp.StatusCode)
+	}
+
+	var page urlScanResponse
+	err = json.NewDecoder(resp.Body).Decode(&page)
+	// Drain what the decoder left so that the response is complete for the cache.
+	discardBody(resp)
+	if err != nil {
+		return "", fmt.Errorf("error parsing urlscan.io JSON: %w", err)
+	}
+
+	for _, entry := range page.Results {
+		seen := result.ParseTimestamp(entry.Task.Time)
+		if strings.Contains(entry.Page.URL, "?") {
+			r := result.Result{URL: entry.Page.URL, Status: result.ParseStatus(entry.Page.Status), MIME: entry.Page.MIMEType}
+			r.SetSeen(seen)
+			if !emit(ctx, out, r) {
+				return "", ctx.Err()
+			}
+		}
+		if entry.Task.URL != entry.Page.URL && strings.Contains(entry.Task.URL, "?") {
+			r := result.Result{URL: entry.Task.URL}
+			r.SetSeen(seen)
+			if !emit(ctx, out, r) {
+				return "", ctx.Err()
+			}
+		}
+	}
+
+	if !page.HasMore || len(page.Results) == 0 {
+		return "", nil
+	}
+	return urlScanCursor(page.Results[len(page.Results)-1].Sort), nil
+}
+
+// urlScanCursor joins the sort values of the last result into a search_after parameter.
+func urlScanCursor(sort []json.RawMessage) string {
+	parts := make([]string, 0, len(sort))
+	for _, raw := range sort {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			parts = append(parts, s)
+		} else {
+			parts = append(parts, string(raw))
+		}
+	}
+	return strings.Join(parts, ",")
+}
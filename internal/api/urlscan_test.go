@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// serveURLScan points URLScanSearchURL at a stand-in server that answers with pages keyed by the
+// search_after parameter ("" for the first page), and records the cursors it was asked for.
+func serveURLScan(t *testing.T, pages map[string]string) *[]string {
+	t.Helper()
+	var cursors []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("search_after")
+		cursors = append(cursors, cursor)
+		body, ok := pages[cursor]
+		if !ok {
+			http.Error(w, "unexpected cursor "+cursor, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	old := URLScanSearchURL
+	URLScanSearchURL = srv.URL + "/api/v1/search/"
+	t.Cleanup(func() { URLScanSearchURL = old })
+	return &cursors
+}
+
+// fetchURLScanResults runs FetchURLScan for domain and returns what it sent.
+func fetchURLScanResults(t *testing.T, domain string) []result.Result {
+	t.Helper()
+	cfg := &config.Config{NoCache: true}
+	if err := config.Validate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	out := make(chan result.Result, 100)
+	ctx := withSource(context.Background(), "urlscan")
+	if err := FetchURLScan(ctx, domain, cfg, out); err != nil {
+		t.Fatalf("FetchURLScan: %v", err)
+	}
+	close(out)
+	var results []result.Result
+	for r := range out {
+		results = append(results, r)
+	}
+	return results
+}
+
+func resultURLs(results []result.Result) []string {
+	var urls []string
+	for _, r := range results {
+		urls = append(urls, r.URL)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func TestFetchURLScanPaging(t *testing.T) {
+	cursors := serveURLScan(t, map[string]string{
+		"": `{"has_more":true,"results":[
+			{"task":{"url":"https://example.com/login?next=/a","time":"2024-05-01T10:00:00.000Z"},
+			 "page":{"url":"https://example.com/a?ref=login","status":"200","mimeType":"text/html"},
+			 "sort":[1714557600000,"c0ffee"]},
+			{"task":{"url":"https://example.com/plain","time":"2024-04-01T10:00:00.000Z"},
+			 "page":{"url":"https://example.com/plain","status":"200","mimeType":"text/html"},
+			 "sort":[1711965600000,"beef"]}]}`,
+		"1711965600000,beef": `{"has_more":false,"results":[
+			{"task":{"url":"https://example.com/search?q=1","time":"2024-03-01T10:00:00.000Z"},
+			 "page":{"url":"https://example.com/search?q=1","status":"404","mimeType":"text/plain"},
+			 "sort":[1709287200000,"f00d"]}]}`,
+	})
+
+	results := fetchURLScanResults(t, "example.com")
+
+	if want := []string{"", "1711965600000,beef"}; !reflect.DeepEqual(*cursors, want) {
+		t.Errorf("search_after cursors = %q, want %q", *cursors, want)
+	}
+	// Unparameterised URLs are skipped, and a task URL equal to its page URL is sent once.
+	want := []string{
+		"https://example.com/a?ref=login",
+		"https://example.com/login?next=/a",
+		"https://example.com/search?q=1",
+	}
+	if got := resultURLs(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("URLs = %q, want %q", got, want)
+	}
+	for _, r := range results {
+		if !reflect.DeepEqual(r.Sources, []string{"urlscan"}) {
+			t.Errorf("%s: sources = %q, want [urlscan]", r.URL, r.Sources)
+		}
+		switch r.URL {
+		case "https://example.com/a?ref=login":
+			if r.Status != 200 || r.MIME != "text/html" {
+				t.Errorf("%s: status %d, MIME %q; want 200, text/html", r.URL, r.Status, r.MIME)
+			}
+		case "https://example.com/login?next=/a":
+			// Task URLs carry no response metadata of their own.
+			if r.Status != 0 || r.MIME != "" {
+				t.Errorf("%s: status %d, MIME %q; want none", r.URL, r.Status, r.MIME)
+			}
+		}
+	}
+}
+
+func TestFetchURLScanStopsOnEmptyPage(t *testing.T) {
+	cursors := serveURLScan(t, map[string]string{
+		"": `{"has_more":true,"results":[
+			{"task":{"url":"https://example.com/a?x=1","time":"2024-05-01T10:00:00.000Z"},
+			 "page":{"url":"https://example.com/a?x=1","status":"200","mimeType":"text/html"},
+			 "sort":[1714557600000,"c0ffee"]}]}`,
+		"1714557600000,c0ffee": `{"has_more":true,"results":[]}`,
+	})
+
+	results := fetchURLScanResults(t, "example.com")
+
+	if want := []string{"", "1714557600000,c0ffee"}; !reflect.DeepEqual(*cursors, want) {
+		t.Errorf("search_after cursors = %q, want %q", *cursors, want)
+	}
+	if got, want := resultURLs(results), []string{"https://example.com/a?x=1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("URLs = %q, want %q", got, want)
+	}
+}
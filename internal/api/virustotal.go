@@ -3,69 +3,281 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/result"
 )
 
-// VirusTotalResponse represents a simplified structure for the VirusTotal domain report.
+// VirusTotalResponse represents a simplified structure for the VirusTotal v2 domain report.
 type VirusTotalResponse struct {
-	DetectedURLs   []struct {
-		URL string `json:"url"`
+	DetectedURLs []struct {
+		URL      string `json:"url"`
+		ScanDate string `json:"scan_date"`
 	} `json:"detected_urls"`
-	UndetectedURLs [][]interface{} `json:"undetected_urls"`
+	UndetectedURLs [][]interface{} `json:"undetected_urls"` // [url, sha256, positives, total, scan_date]
 }
 
-// FetchVirusTotal fetches URLs from VirusTotal for the given domain.
-func FetchVirusTotal(ctx context.Context, domain string, cfg *config.Config) ([]string, error) {
-	if cfg.VirusTotalAPIKey == "" {
+func init() {
+	Register(NewSource("virustotal", FetchVirusTotal, config.KeyVirusTotalAPIKey))
+}
+
+// VirusTotalV3URL is the base URL of the VirusTotal API v3.
+const VirusTotalV3URL = "https://www.virustotal.com/api/v3"
+
+// virusTotalPageLimit is the largest page size the v3 relationship endpoints accept.
+const virusTotalPageLimit = 40
+
+// virusTotalV3Auth sends pooled VirusTotal keys in the x-apikey header used by API v3.
+var virusTotalV3Auth = KeyAuth{Credential: config.KeyVirusTotalAPIKey, Header: "x-apikey"}
+
+// virusTotalV2Auth sends pooled VirusTotal keys in the apikey query parameter required by API v2.
+var virusTotalV2Auth = KeyAuth{Credential: config.KeyVirusTotalAPIKey, Param: "apikey"}
+
+var (
+	// errVirusTotalV3Unavailable is returned when the key is valid but has no access to the v3
+	// relationship endpoints (a 403 other than a quota error), as with non-premium keys.
+	errVirusTotalV3Unavailable = errors.New("VirusTotal API v3 is not available for this key")
+	// errVirusTotalKeyRejected is returned when VirusTotal does not accept the key at all (401).
+	errVirusTotalKeyRejected = errors.New("VirusTotal rejected the API key")
+	// errVirusTotalQuota is returned when every key's quota is exhausted.
+	errVirusTotalQuota = errors.New("VirusTotal API quota exceeded")
+)
+
+// virusTotalV3Refused is set once v3 has refused the key, so that the rest of the run goes
+// straight to v2 instead of probing v3 again for every domain.
+var virusTotalV3Refused int32
+
+// virusTotalURLAttributes holds the fields of a v3 URL object that goParams uses.
+type virusTotalURLAttributes struct {
+	URL                     string            `json:"url"`
+	FirstSubmissionDate     int64             `json:"first_submission_date"`
+	LastAnalysisDate        int64             `json:"last_analysis_date"`
+	LastHTTPResponseCode    int               `json:"last_http_response_code"`
+	LastHTTPResponseHeaders map[string]string `json:"last_http_response_headers"`
+}
+
+// virusTotalPage is one page of a v3 relationship listing.
+type virusTotalPage struct {
+	Data []struct {
+		ID         string                  `json:"id"`
+		Attributes virusTotalURLAttributes `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		Cursor string `json:"cursor"`
+	} `json:"meta"`
+}
+
+// FetchVirusTotal fetches URLs from VirusTotal for the given domain and sends them, with their capture
+// metadata, to out. It uses API v3, walking the domain's "urls" relationship (and, with subdomains
+// included, the "urls" of every entry in its "subdomains" relationship) with cursor pagination.
+// Keys without v3 access fall back to the v2 domain report, for this and every later domain of the
+// run; rejected keys and exhausted quotas are reported as errors instead.
+func FetchVirusTotal(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error {
+	if cfg.Credential(config.KeyVirusTotalAPIKey) == "" {
 		color.Yellow("No VirusTotal API key provided. Skipping VirusTotal lookup for %s", domain)
-		return nil, nil
+		return nil
 	}
-	apiURL := fmt.Sprintf("https://www.virustotal.com/vtapi/v2/domain/report?apikey=%s&domain=%s", cfg.VirusTotalAPIKey, domain)
 	color.Blue("[*] Fetching from VirusTotal for domain: %s", domain)
 
-	resp, err := GetWithRandomUA(ctx, apiURL, cfg)
+	if atomic.LoadInt32(&virusTotalV3Refused) == 1 {
+		return fetchVirusTotalV2Page(ctx, domain, cfg, out)
+	}
+	err := fetchVirusTotalURLs(ctx, domain, cfg, out)
+	if errors.Is(err, errVirusTotalV3Unavailable) {
+		if atomic.CompareAndSwapInt32(&virusTotalV3Refused, 0, 1) {
+			color.Yellow("VirusTotal API v3 is not available for the key; using API v2 for the rest of the run")
+		}
+		return fetchVirusTotalV2Page(ctx, domain, cfg, out)
+	}
+	if err != nil || !cfg.IncludeSubdomains {
+		return err
+	}
+
+	// Subdomain listings are not checkpointed: their pages only name subdomains, whose own "urls"
+	// walks are checkpointed.
+	return walkVirusTotal(ctx, cfg, "/domains/"+url.PathEscape(domain)+"/subdomains", false, nil, func(ctx context.Context, page *virusTotalPage) bool {
+		for _, sub := range page.Data {
+			if sub.ID == "" || strings.EqualFold(sub.ID, domain) {
+				continue
+			}
+			if err := fetchVirusTotalURLs(ctx, sub.ID, cfg, out); err != nil {
+				color.Yellow("Error fetching VirusTotal URLs for subdomain %s: %v", sub.ID, err)
+				reportError(ctx, fmt.Errorf("subdomain %s: %w", sub.ID, err))
+			}
+			if ctx.Err() != nil {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// fetchVirusTotalURLs walks the v3 "urls" relationship of a domain.
+func fetchVirusTotalURLs(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error {
+	return walkVirusTotal(ctx, cfg, "/domains/"+url.PathEscape(domain)+"/urls", true, out, func(ctx context.Context, page *virusTotalPage) bool {
+		for _, obj := range page.Data {
+			attrs := obj.Attributes
+			if attrs.URL == "" || !strings.Contains(attrs.URL, "?") {
+				continue
+			}
+			r := result.Result{URL: attrs.URL, Status: attrs.LastHTTPResponseCode}
+			for key, value := range attrs.LastHTTPResponseHeaders {
+				if strings.EqualFold(key, "Content-Type") {
+					r.MIME = result.MediaType(value)
+				}
+			}
+			if attrs.FirstSubmissionDate > 0 {
+				r.SetSeen(time.Unix(attrs.FirstSubmissionDate, 0))
+			}
+			if attrs.LastAnalysisDate > 0 {
+				r.SetSeen(time.Unix(attrs.LastAnalysisDate, 0))
+			}
+			if !emit(ctx, out, r) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// walkVirusTotal requests every page of a v3 relationship, calling handle for each one until the
+// cursor runs out or handle returns false. The API key is sent in the x-apikey header.
+// If resumable, each page is checkpointed (see fetchPage), replaying to out the URLs that handle emitted.
+func walkVirusTotal(ctx context.Context, cfg *config.Config, path string, resumable bool, out chan<- result.Result, handle func(context.Context, *virusTotalPage) bool) error {
+	cursor := ""
+	for {
+		q := url.Values{}
+		q.Set("limit", strconv.Itoa(virusTotalPageLimit))
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		}
+		apiURL := VirusTotalV3URL + path + "?" + q.Encode()
+
+		fetch := func(ctx context.Context) (string, error) {
+			return fetchVirusTotalPage(ctx, apiURL, cfg, handle)
+		}
+		var next string
+		var err error
+		if resumable {
+			next, err = fetchPage(ctx, out, apiURL, fetch)
+		} else {
+			next, err = fetch(ctx)
+		}
+		if err != nil || next == "" {
+			return err
+		}
+		cursor = next
+	}
+}
+
+// fetchVirusTotalPage requests a single page of a v3 relationship and hands it to handle. It returns
+// the cursor of the following page, or an empty string on the last page.
+func fetchVirusTotalPage(ctx context.Context, apiURL string, cfg *config.Config, handle func(context.Context, *virusTotalPage) bool) (string, error) {
+	resp, err := GetWithAPIKey(ctx, apiURL, cfg, virusTotalV3Auth, nil)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching from VirusTotal: %w", err)
+		return "", fmt.Errorf("error fetching from VirusTotal: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		code := providerErrorCode(resp)
+		discardBody(resp)
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			return "", nil
+		case resp.StatusCode == http.StatusTooManyRequests || code == quotaErrorCode:
+			return "", errVirusTotalQuota
+		case resp.StatusCode == http.StatusUnauthorized:
+			return "", fmt.Errorf("%w (%s)", errVirusTotalKeyRejected, code)
+		case resp.StatusCode == http.StatusForbidden:
+			return "", errVirusTotalV3Unavailable
+		}
+		return "", fmt.Errorf("VirusTotal returned status code %d", resp.StatusCode)
+	}
+
+	var page virusTotalPage
+	err = json.NewDecoder(resp.Body).Decode(&page)
+	// Drain what the decoder left so that the response is complete for the cache.
+	discardBody(resp)
+	if err != nil {
+		return "", fmt.Errorf("error parsing VirusTotal JSON: %w", err)
+	}
+	if !handle(ctx, &page) {
+		return "", ctx.Err()
+	}
+	if len(page.Data) == 0 {
+		return "", nil
+	}
+	return page.Meta.Cursor, nil
+}
+
+// fetchVirusTotalV2Page fetches the v2 domain report as a single checkpointed page.
+func fetchVirusTotalV2Page(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error {
+	_, err := fetchPage(ctx, out, virusTotalV2URL(domain), func(ctx context.Context) (string, error) {
+		return "", fetchVirusTotalV2(ctx, domain, cfg, out)
+	})
+	return err
+}
+
+// virusTotalV2URL returns the v2 domain report URL for domain, without the API key.
+func virusTotalV2URL(domain string) string {
+	return "https://www.virustotal.com/vtapi/v2/domain/report?domain=" + url.QueryEscape(domain)
+}
+
+// fetchVirusTotalV2 fetches URLs from the deprecated v2 domain report, which only returns the first
+// page of detected and undetected URLs. v2 requires the API key in the query string.
+func fetchVirusTotalV2(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error {
+	resp, err := GetWithAPIKey(ctx, virusTotalV2URL(domain), cfg, virusTotalV2Auth, nil)
+	if err != nil {
+		return fmt.Errorf("error fetching from VirusTotal v2: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("VirusTotal returned status code %d", resp.StatusCode)
+		return fmt.Errorf("VirusTotal returned status code %d", resp.StatusCode)
 	}
 
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("error reading VirusTotal response: %w", err)
+		return fmt.Errorf("error reading VirusTotal response: %w", err)
 	}
 
 	var vtResp VirusTotalResponse
 	if err := json.Unmarshal(bodyBytes, &vtResp); err != nil {
-		return nil, fmt.Errorf("error parsing VirusTotal JSON: %w", err)
+		return fmt.Errorf("error parsing VirusTotal JSON: %w", err)
 	}
 
-	urlSet := make(map[string]struct{})
 	for _, entry := range vtResp.DetectedURLs {
 		if entry.URL != "" && strings.Contains(entry.URL, "?") {
-			urlSet[entry.URL] = struct{}{}
+			r := result.Result{URL: entry.URL}
+			r.SetSeen(result.ParseTimestamp(entry.ScanDate))
+			if !emit(ctx, out, r) {
+				return ctx.Err()
+			}
 		}
 	}
 	for _, arr := range vtResp.UndetectedURLs {
 		if len(arr) > 0 {
 			if urlStr, ok := arr[0].(string); ok && strings.Contains(urlStr, "?") {
-				urlSet[urlStr] = struct{}{}
+				r := result.Result{URL: urlStr}
+				if len(arr) >= 5 {
+					if scanDate, ok := arr[4].(string); ok {
+						r.SetSeen(result.ParseTimestamp(scanDate))
+					}
+				}
+				if !emit(ctx, out, r) {
+					return ctx.Err()
+				}
 			}
 		}
 	}
-
-	var results []string
-	for u := range urlSet {
-		results = append(results, u)
-	}
-	return results, nil
+	return nil
 }
@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/fatih/color"
 	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/result"
 )
 
 // WayBackException is returned when the Wayback Machine response indicates an error.
@@ -22,6 +25,13 @@ func (w *WayBackException) Error() string {
 	return w.Message
 }
 
+func init() {
+	Register(NewSource("wayback", FetchWayback))
+}
+
+// WaybackCDXURL is the Wayback Machine CDX server endpoint.
+const WaybackCDXURL = "https://web.archive.org/cdx/search/cdx"
+
 // fixArchiveOrgUrl removes any trailing "%0A" or "%0a" from the provided URL.
 func fixArchiveOrgUrl(urlStr string) string {
 	lower := strings.ToLower(urlStr)
@@ -31,65 +41,137 @@ func fixArchiveOrgUrl(urlStr string) string {
 	return urlStr
 }
 
-// FetchWayback queries the Wayback Machine CDX API for archived URLs of the given domain.
-// It uses an extended timeout (e.g. 2 minutes) so that large datasets can load.
-// Returns a deduplicated slice of original URLs that include query parameters.
-func FetchWayback(ctx context.Context, domain string, cfg *config.Config) ([]string, error) {
-	// For simplicity, we use a default collapse value.
-	collapse := "/*"
-	apiURL := fmt.Sprintf("https://web.archive.org/cdx/search/cdx?url=%s%s&fl=timestamp,original,mimetype,statuscode,digest", domain, collapse)
-	color.Blue("[*] Fetching from Wayback Machine: %s", apiURL)
-
-	// Create a new context with an extended timeout for Wayback.
-	waybackTimeout := 2 * time.Minute
-	waybackCtx, cancel := context.WithTimeout(ctx, waybackTimeout)
-	defer cancel()
-
-	// Use the shared HTTP client with the extended context.
-	resp, err := GetWithRandomUA(waybackCtx, apiURL, cfg)
+// waybackQuery builds the CDX query for a domain, or for the domain and all of its subdomains
+// ("*.domain") when includeSubdomains is set. Filtering happens server-side: only captures whose
+// original URL has a query string are returned, collapsed by URL key, skipping 404s, revisit records
+// and static media.
+func waybackQuery(domain string, includeSubdomains bool) url.Values {
+	q := url.Values{}
+	if includeSubdomains {
+		q.Set("url", "*."+domain)
+	} else {
+		q.Set("url", domain+"/*")
+	}
+	q.Set("fl", "original,timestamp,statuscode,mimetype")
+	q.Set("collapse", "urlkey")
+	q.Add("filter", `original:.*\?.*`)
+	q.Add("filter", "!statuscode:404")
+	q.Add("filter", "!mimetype:(warc/revisit|image/.*|font/.*|video/.*|audio/.*)")
+	return q
+}
+
+// waybackNumPages asks the CDX server how many pages the query spans.
+func waybackNumPages(ctx context.Context, q url.Values, cfg *config.Config) (int, error) {
+	pq := cloneValues(q)
+	pq.Set("showNumPages", "true")
+	apiURL := WaybackCDXURL + "?" + pq.Encode()
+	color.Blue("[*] Fetching Wayback Machine page count from: %s", apiURL)
+
+	resp, err := GetWithRandomUA(ctx, apiURL, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching from Wayback: %w", err)
+		return 0, fmt.Errorf("error fetching Wayback page count: %w", err)
 	}
 	defer resp.Body.Close()
-
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Wayback Machine returned status code %d", resp.StatusCode)
+		return 0, fmt.Errorf("Wayback Machine returned status code %d for page count", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return 0, fmt.Errorf("error reading Wayback page count: %w", err)
+	}
+	pages, err := strconv.Atoi(strings.TrimSpace(string(body)))
+	if err != nil {
+		return 0, &WayBackException{Message: fmt.Sprintf("unexpected Wayback page count response: %q", strings.TrimSpace(string(body)))}
+	}
+	return pages, nil
+}
+
+// FetchWayback queries the Wayback Machine CDX API for archived URLs of the given domain.
+// The result set is split into CDX pages (showNumPages/page) which are fetched concurrently,
+// bounded by cfg.Concurrency, and each page is streamed line by line to out.
+func FetchWayback(ctx context.Context, domain string, cfg *config.Config, out chan<- result.Result) error {
+	q := waybackQuery(domain, cfg.IncludeSubdomains)
+	numPages, err := waybackNumPages(ctx, q, cfg)
+	if err != nil {
+		return err
+	}
+	if numPages == 0 {
+		color.Yellow("Wayback Machine returned zero pages for %s", domain)
+		return nil
 	}
+	color.Blue("[*] Wayback Machine reports %d pages for %s", numPages, domain)
+
+	sem := make(chan struct{}, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for page := 0; page < numPages; page++ {
+		pq := cloneValues(q)
+		pq.Set("page", strconv.Itoa(page))
+		pageURL := WaybackCDXURL + "?" + pq.Encode()
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(pageURL string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			_, err := fetchPage(ctx, out, pageURL, func(ctx context.Context) (string, error) {
+				return "", fetchWaybackPage(ctx, pageURL, cfg, out)
+			})
+			if err != nil {
+				color.Yellow("Error processing Wayback page %s: %v", pageURL, err)
+				reportError(ctx, fmt.Errorf("page %s: %w", pageURL, err))
+			}
+		}(pageURL)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+// fetchWaybackPage requests a single CDX page and sends each original URL, with its capture
+// timestamp, status code and MIME type, to out.
+func fetchWaybackPage(ctx context.Context, pageURL string, cfg *config.Config, out chan<- result.Result) error {
+	color.Blue("[*] Fetching from Wayback Machine: %s", pageURL)
+	// Each attempt is bounded by the source's timeout (2 minutes by default for the Wayback Machine)
+	// so that one slow page cannot stall the whole domain.
+	resp, err := GetWithRandomUA(ctx, pageURL, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("error reading Wayback response: %w", err)
+		return fmt.Errorf("error fetching from Wayback: %w", err)
 	}
-	bodyStr := string(bodyBytes)
-	lowerBody := strings.ToLower(bodyStr)
-	if strings.Contains(lowerBody, "wayback machine has not archived that url") ||
-		strings.Contains(lowerBody, "snapshot cannot be displayed due to an internal error") {
-		return nil, &WayBackException{Message: "Wayback Machine returned an error response"}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Wayback Machine returned status code %d", resp.StatusCode)
 	}
 
-	scanner := bufio.NewScanner(strings.NewReader(bodyStr))
-	urlSet := make(map[string]struct{})
+	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" {
 			continue
 		}
+		lowerLine := strings.ToLower(line)
+		if strings.Contains(lowerLine, "wayback machine has not archived that url") ||
+			strings.Contains(lowerLine, "snapshot cannot be displayed due to an internal error") {
+			return &WayBackException{Message: "Wayback Machine returned an error response"}
+		}
 		fields := strings.Fields(line)
-		if len(fields) < 2 {
+		originalURL := fixArchiveOrgUrl(fields[0])
+		if !strings.Contains(originalURL, "?") {
 			continue
 		}
-		originalURL := fixArchiveOrgUrl(fields[1])
-		if strings.Contains(originalURL, "?") {
-			urlSet[originalURL] = struct{}{}
+		r := result.Result{URL: originalURL}
+		if len(fields) >= 4 {
+			r.SetSeen(result.ParseTimestamp(fields[1]))
+			r.Status = result.ParseStatus(fields[2])
+			if fields[3] != "-" {
+				r.MIME = fields[3]
+			}
+		}
+		if !emit(ctx, out, r) {
+			return ctx.Err()
 		}
 	}
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error scanning Wayback response: %w", err)
-	}
-
-	var results []string
-	for url := range urlSet {
-		results = append(results, url)
+		return fmt.Errorf("error scanning Wayback response: %w", err)
 	}
-	return results, nil
+	return nil
 }
@@ -0,0 +1,133 @@
+// Package archive reads the URLs recorded in local web archive files: WARC and WAT files, as written
+// by crawlers and published by Common Crawl, and CDX and CDXJ capture indexes. Files may be gzipped,
+// including the multi-member gzip used for .warc.gz.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// Format identifies the layout of an archive file.
+type Format string
+
+const (
+	FormatWARC Format = "warc" // WARC records, including WAT metadata records.
+	FormatCDX  Format = "cdx"  // Space-separated CDX lines, optionally with a " CDX" field legend.
+	FormatCDXJ Format = "cdxj" // "urlkey timestamp {json}" lines.
+)
+
+// ReadFile streams every captured URL in the file at path to fn, until the file ends or fn returns
+// false. The format is detected from the content, so a Common Crawl index shard such as cdx-00000.gz
+// is recognised without a telling extension. Results carry the capture time, status and MIME type
+// when the file records them.
+func ReadFile(path string, fn func(result.Result) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := decompress(bufio.NewReaderSize(f, 64*1024))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	format, err := detect(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	switch format {
+	case FormatWARC:
+		err = readWARC(r, fn)
+	case FormatCDXJ:
+		err = readCDXJ(r, fn)
+	default:
+		err = readCDX(r, fn)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// decompress returns r itself, or a gzip reader over it if it starts with the gzip magic number.
+func decompress(r *bufio.Reader) (*bufio.Reader, error) {
+	magic, err := r.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return r, nil
+	}
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return bufio.NewReaderSize(gz, 64*1024), nil
+}
+
+// detect sniffs the format from the first line of r without consuming it. CDXJ metadata lines
+// ("!meta ...") before it are skipped.
+func detect(r *bufio.Reader) (Format, error) {
+	head, err := r.Peek(4096)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", err
+	}
+	head = bytes.TrimLeft(head, "\r\n")
+	for bytes.HasPrefix(head, []byte("!")) {
+		i := bytes.IndexByte(head, '\n')
+		if i < 0 {
+			break
+		}
+		head = bytes.TrimLeft(head[i+1:], "\r\n")
+	}
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+	switch {
+	case bytes.HasPrefix(head, []byte("WARC/")):
+		return FormatWARC, nil
+	case bytes.HasPrefix(bytes.TrimSpace(head), []byte("CDX ")):
+		return FormatCDX, nil
+	}
+	// A CDXJ line has a JSON block in its third field.
+	fields := bytes.SplitN(head, []byte(" "), 3)
+	if len(fields) == 3 && bytes.HasPrefix(fields[2], []byte("{")) {
+		return FormatCDXJ, nil
+	}
+	if len(bytes.Fields(head)) >= 3 {
+		return FormatCDX, nil
+	}
+	return "", fmt.Errorf("unrecognised archive format")
+}
+
+// readLines calls fn with each line of r, without its line ending, until r ends or fn returns false.
+func readLines(r *bufio.Reader, fn func(line string) bool) error {
+	for {
+		line, err := r.ReadString('\n')
+		if len(line) > 0 {
+			line = trimEOL(line)
+			if line != "" && !fn(line) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func trimEOL(s string) string {
+	for len(s) > 0 && (s[len(s)-1] == '\n' || s[len(s)-1] == '\r') {
+		s = s[:len(s)-1]
+	}
+	return s
+}
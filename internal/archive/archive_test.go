@@ -0,0 +1,123 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// readAll reads the file at path and describes each result as "URL status MIME capture-time".
+func readAll(t *testing.T, path string) []string {
+	t.Helper()
+	var got []string
+	err := ReadFile(path, func(r result.Result) bool {
+		seen := "-"
+		if r.LastSeen != nil {
+			seen = r.LastSeen.UTC().Format(time.RFC3339)
+		}
+		got = append(got, fmt.Sprintf("%s %d %q %s", r.URL, r.Status, r.MIME, seen))
+		return true
+	})
+	if err != nil {
+		t.Fatalf("ReadFile(%s): %v", path, err)
+	}
+	return got
+}
+
+func TestReadFileFormats(t *testing.T) {
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"sample.warc", []string{
+			`https://example.com/search?q=shoes 0 "" 2024-05-01T10:00:01Z`,
+			`https://example.com/search?q=shoes 200 "text/html" 2024-05-01T10:00:02Z`,
+			`https://example.com/missing?id=9 404 "text/plain" 2024-05-02T08:30:00Z`,
+		}},
+		// WAT records yield the page and its links, resolved against the page; non-HTTP links are dropped.
+		{"sample.wat", []string{
+			`https://example.com/blog/post?id=1 200 "text/html" 2024-06-01T12:00:00Z`,
+			`https://example.com/search?q=next 0 "" -`,
+			`https://cdn.example.net/app.js?v=3 0 "" -`,
+			`https://example.com/blog/page?p=2 0 "" -`,
+		}},
+		{"sample.cdx", []string{
+			`https://example.com/search?q=shoes 200 "text/html" 2024-05-01T10:00:02Z`,
+			`https://example.com/about 301 "" 2024-05-02T00:00:00Z`,
+		}},
+		// Metadata lines and lines with a broken JSON block are skipped.
+		{"sample.cdxj", []string{
+			`https://example.com/search?q=shoes 200 "text/html" 2024-05-01T10:00:02Z`,
+			`https://example.com/item?id=7 404 "" 2024-05-03T00:00:00Z`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if got := readAll(t, filepath.Join("testdata", tt.file)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("results:\n%s\nwant:\n%s", join(got), join(tt.want))
+			}
+		})
+	}
+}
+
+// TestReadFileMultiMemberGzip reads a .warc.gz in which each record is its own gzip member, as
+// crawlers write them.
+func TestReadFileMultiMemberGzip(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "sample.warc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	for _, record := range bytes.SplitAfter(data, []byte("\r\n\r\nWARC/")) {
+		zw := gzip.NewWriter(&buf)
+		zw.Write(record)
+		zw.Close()
+	}
+	path := filepath.Join(t.TempDir(), "sample.warc.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want := readAll(t, filepath.Join("testdata", "sample.warc"))
+	if got := readAll(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("gzipped results:\n%s\nwant:\n%s", join(got), join(want))
+	}
+}
+
+func TestReadFileStops(t *testing.T) {
+	n := 0
+	err := ReadFile(filepath.Join("testdata", "sample.warc"), func(result.Result) bool {
+		n++
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("fn called %d times after asking to stop, want 1", n)
+	}
+}
+
+func TestReadFileUnrecognised(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReadFile(path, func(result.Result) bool { return true }); err == nil {
+		t.Error("ReadFile succeeded on a file that is not an archive")
+	}
+}
+
+func join(lines []string) string {
+	var b bytes.Buffer
+	for _, l := range lines {
+		b.WriteString("\t" + l + "\n")
+	}
+	return b.String()
+}
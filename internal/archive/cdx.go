@@ -0,0 +1,83 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// defaultCDXFields is the field legend of CDX files without a header: the 11-field format written by
+// the Wayback Machine ("N b a m s k r M S V g"). The 9- and 7-field variants share its first five fields.
+var defaultCDXFields = []string{"N", "b", "a", "m", "s", "k", "r", "M", "S", "V", "g"}
+
+// readCDX reads a CDX index. A leading " CDX ..." legend names the fields of each line; a is the
+// original URL, b the capture timestamp, m the MIME type and s the status code.
+func readCDX(r *bufio.Reader, fn func(result.Result) bool) error {
+	fields := defaultCDXFields
+	first := true
+	return readLines(r, func(line string) bool {
+		if first {
+			first = false
+			if legend := strings.Fields(line); len(legend) > 0 && legend[0] == "CDX" {
+				fields = legend[1:]
+				return true
+			}
+		}
+		values := strings.Fields(line)
+		var res result.Result
+		for i, name := range fields {
+			if i >= len(values) {
+				break
+			}
+			switch name {
+			case "a":
+				res.URL = values[i]
+			case "b":
+				res.SetSeen(result.ParseTimestamp(values[i]))
+			case "m":
+				if values[i] != "-" {
+					res.MIME = values[i]
+				}
+			case "s":
+				res.Status = result.ParseStatus(values[i])
+			}
+		}
+		if res.URL == "" {
+			return true
+		}
+		return fn(res)
+	})
+}
+
+// cdxjBlock is the JSON block of a CDXJ line, as written by pywb and Common Crawl.
+type cdxjBlock struct {
+	URL    string `json:"url"`
+	MIME   string `json:"mime"`
+	Status string `json:"status"`
+}
+
+// readCDXJ reads a CDXJ index. Lines starting with "!" are metadata and are skipped, as are lines
+// whose JSON block cannot be parsed.
+func readCDXJ(r *bufio.Reader, fn func(result.Result) bool) error {
+	return readLines(r, func(line string) bool {
+		if strings.HasPrefix(line, "!") {
+			return true
+		}
+		parts := strings.SplitN(line, " ", 3)
+		if len(parts) < 3 {
+			return true
+		}
+		var block cdxjBlock
+		if err := json.Unmarshal([]byte(parts[2]), &block); err != nil || block.URL == "" {
+			return true
+		}
+		res := result.Result{URL: block.URL, Status: result.ParseStatus(block.Status)}
+		if block.MIME != "-" {
+			res.MIME = block.MIME
+		}
+		res.SetSeen(result.ParseTimestamp(parts[1]))
+		return fn(res)
+	})
+}
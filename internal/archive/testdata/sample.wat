@@ -0,0 +1,18 @@
+WARC/1.0
+WARC-Type: warcinfo
+WARC-Date: 2024-06-01T12:00:00Z
+Content-Type: application/warc-fields
+Content-Length: 13
+
+format: WAT
+
+
+WARC/1.0
+WARC-Type: metadata
+WARC-Target-URI: https://example.com/blog/post?id=1
+WARC-Date: 2024-06-01T12:00:05Z
+Content-Type: application/json
+Content-Length: 546
+
+{"Envelope": {"WARC-Header-Metadata": {"WARC-Type": "response", "WARC-Target-URI": "https://example.com/blog/post?id=1", "WARC-Date": "2024-06-01T12:00:00Z"}, "Payload-Metadata": {"HTTP-Response-Metadata": {"Response-Message": {"Status": "200"}, "Headers": {"Content-Type": "text/html; charset=utf-8"}, "HTML-Metadata": {"Links": [{"url": "/search?q=next", "path": "A@/href"}, {"url": "https://cdn.example.net/app.js?v=3", "path": "SCRIPT@/src"}, {"url": "mailto:info@example.com", "path": "A@/href"}, {"url": "page?p=2", "path": "A@/href"}]}}}}}
+
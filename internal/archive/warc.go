@@ -0,0 +1,159 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// readWARC reads the records of a WARC file. Every record with a WARC-Target-URI yields that URL;
+// response records add the HTTP status and Content-Type of their payload, and WAT metadata records
+// also yield the links extracted from the captured page.
+func readWARC(r *bufio.Reader, fn func(result.Result) bool) error {
+	tp := textproto.NewReader(r)
+	for {
+		version, err := nextVersionLine(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(version, "WARC/") {
+			return fmt.Errorf("malformed WARC record: unexpected %q", version)
+		}
+		header, err := tp.ReadMIMEHeader()
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("malformed WARC record header: %w", err)
+		}
+		length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+		if err != nil || length < 0 {
+			return fmt.Errorf("WARC record without a valid Content-Length")
+		}
+		block := io.LimitReader(r, length)
+		ok := readWARCRecord(header, block, fn)
+		// Skip whatever the record handler did not read, up to the next record.
+		if _, err := io.Copy(io.Discard, block); err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+}
+
+// nextVersionLine skips the blank lines separating records and returns the next "WARC/x.y" line.
+func nextVersionLine(r *bufio.Reader) (string, error) {
+	for {
+		line, err := r.ReadString('\n')
+		if trimmed := trimEOL(line); trimmed != "" {
+			return trimmed, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// readWARCRecord passes the URLs of one record to fn and returns false if fn asked to stop.
+func readWARCRecord(header textproto.MIMEHeader, block io.Reader, fn func(result.Result) bool) bool {
+	target := strings.Trim(header.Get("WARC-Target-URI"), "<>")
+	contentType := header.Get("Content-Type")
+	switch header.Get("WARC-Type") {
+	case "warcinfo":
+		return true
+	case "metadata":
+		if strings.HasPrefix(contentType, "application/json") {
+			return readWATRecord(block, fn)
+		}
+	}
+	if target == "" {
+		return true
+	}
+	res := result.Result{URL: target}
+	res.SetSeen(result.ParseTimestamp(header.Get("WARC-Date")))
+	if header.Get("WARC-Type") == "response" && strings.HasPrefix(contentType, "application/http") {
+		if resp, err := http.ReadResponse(bufio.NewReader(block), nil); err == nil {
+			res.Status = resp.StatusCode
+			res.MIME = result.MediaType(resp.Header.Get("Content-Type"))
+			resp.Body.Close()
+		}
+	}
+	return fn(res)
+}
+
+// watEnvelope is the part of a WAT metadata record that goParams uses.
+type watEnvelope struct {
+	Envelope struct {
+		Header struct {
+			Type      string `json:"WARC-Type"`
+			TargetURI string `json:"WARC-Target-URI"`
+			Date      string `json:"WARC-Date"`
+		} `json:"WARC-Header-Metadata"`
+		Payload struct {
+			Response struct {
+				Message struct {
+					Status json.Number `json:"Status"`
+				} `json:"Response-Message"`
+				Headers map[string]interface{} `json:"Headers"`
+				HTML    struct {
+					Links []struct {
+						URL string `json:"url"`
+					} `json:"Links"`
+				} `json:"HTML-Metadata"`
+			} `json:"HTTP-Response-Metadata"`
+		} `json:"Payload-Metadata"`
+	} `json:"Envelope"`
+}
+
+// readWATRecord passes the target URI of a WAT record to fn, followed by the links found on the
+// page, resolved against it. Records that cannot be decoded are skipped.
+func readWATRecord(block io.Reader, fn func(result.Result) bool) bool {
+	var wat watEnvelope
+	if err := json.NewDecoder(block).Decode(&wat); err != nil {
+		return true
+	}
+	h := wat.Envelope.Header
+	if h.TargetURI == "" {
+		return true
+	}
+	res := result.Result{URL: h.TargetURI}
+	res.SetSeen(result.ParseTimestamp(h.Date))
+	if h.Type == "response" {
+		resp := wat.Envelope.Payload.Response
+		res.Status = result.ParseStatus(resp.Message.Status.String())
+		if ct, ok := resp.Headers["Content-Type"].(string); ok {
+			res.MIME = result.MediaType(ct)
+		}
+	}
+	if !fn(res) {
+		return false
+	}
+
+	base, err := url.Parse(h.TargetURI)
+	if err != nil {
+		return true
+	}
+	for _, link := range wat.Envelope.Payload.Response.HTML.Links {
+		ref, err := url.Parse(strings.TrimSpace(link.URL))
+		if err != nil || link.URL == "" {
+			continue
+		}
+		resolved := base.ResolveReference(ref)
+		if resolved.Scheme != "http" && resolved.Scheme != "https" {
+			continue
+		}
+		if !fn(result.Result{URL: resolved.String()}) {
+			return false
+		}
+	}
+	return true
+}
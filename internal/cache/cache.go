@@ -0,0 +1,279 @@
+// Package cache stores provider responses on disk, keyed by request URL, so that repeated runs
+// against the same targets can be served locally within a time-to-live.
+package cache
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// secretParams are query parameters removed from request URLs before they are used as keys, so that
+// API keys never end up in the cache.
+var secretParams = []string{"apikey", "api_key"}
+
+// Key returns the cache key of a request URL: the URL with secret query parameters removed.
+func Key(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	stripped := false
+	for _, name := range secretParams {
+		for existing := range q {
+			if strings.EqualFold(existing, name) {
+				q.Del(existing)
+				stripped = true
+			}
+		}
+	}
+	if stripped {
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// meta is the first line of an entry file; the response body follows it.
+type meta struct {
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Stored time.Time   `json:"stored"`
+}
+
+// Cache is a directory of cached responses. It is safe for concurrent use: entries are written to
+// temporary files and renamed into place.
+type Cache struct {
+	dir string
+}
+
+// DefaultDir returns the default cache directory, $XDG_CACHE_HOME/goParams/responses
+// (~/.cache/goParams/responses if unset).
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goParams", "responses"), nil
+}
+
+// Open returns the cache stored in dir, creating the directory if needed.
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Dir returns the directory the cache is stored in.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// path returns the entry file for key, sharded by the first two hex digits of its hash.
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, name[:2], name)
+}
+
+// Get returns the cached response for key if it was stored less than ttl ago. The caller must close
+// the response body.
+func (c *Cache) Get(key string, ttl time.Duration) (*http.Response, bool) {
+	f, err := os.Open(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	reader := bufio.NewReader(f)
+	m, err := readMeta(reader)
+	if err != nil || m.URL != key || time.Since(m.Stored) > ttl {
+		f.Close()
+		return nil, false
+	}
+	return &http.Response{
+		Status:     http.StatusText(m.Status),
+		StatusCode: m.Status,
+		Header:     m.Header,
+		Body:       readCloser{Reader: reader, Closer: f},
+	}, true
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func readMeta(r *bufio.Reader) (meta, error) {
+	var m meta
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(line, &m)
+	return m, err
+}
+
+// Entry is a cache entry being written. Its body is stored with Write and made visible by Commit;
+// Abort discards it.
+type Entry struct {
+	c    *Cache
+	key  string
+	tmp  *os.File
+	w    *bufio.Writer
+	err  error
+	done bool
+}
+
+// Create starts a new entry for key holding a response with the given status and header.
+func (c *Cache) Create(key string, status int, header http.Header) (*Entry, error) {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	e := &Entry{c: c, key: key, tmp: tmp, w: bufio.NewWriter(tmp)}
+	line, err := json.Marshal(meta{URL: key, Status: status, Header: header, Stored: time.Now().UTC()})
+	if err != nil {
+		e.Abort()
+		return nil, err
+	}
+	if _, err := e.w.Write(append(line, '\n')); err != nil {
+		e.Abort()
+		return nil, err
+	}
+	return e, nil
+}
+
+// Write appends p to the entry's body. Write errors are remembered and reported by Commit.
+func (e *Entry) Write(p []byte) (int, error) {
+	if e.err == nil {
+		_, e.err = e.w.Write(p)
+	}
+	return len(p), nil
+}
+
+// Commit stores the entry, replacing any earlier entry for the same key.
+func (e *Entry) Commit() error {
+	if e.done {
+		return nil
+	}
+	e.done = true
+	err := e.err
+	if err == nil {
+		err = e.w.Flush()
+	}
+	if closeErr := e.tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(e.tmp.Name(), e.c.path(e.key))
+	}
+	if err != nil {
+		os.Remove(e.tmp.Name())
+	}
+	return err
+}
+
+// Abort discards the entry. It does nothing after Commit.
+func (e *Entry) Abort() {
+	if e.done {
+		return
+	}
+	e.done = true
+	e.tmp.Close()
+	os.Remove(e.tmp.Name())
+}
+
+// Stats describes the contents of a cache.
+type Stats struct {
+	Entries int
+	Bytes   int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// Stats walks the cache and returns its size and age range.
+func (c *Cache) Stats() (Stats, error) {
+	var st Stats
+	err := c.walk(func(path string, info fs.FileInfo, m meta, err error) error {
+		st.Entries++
+		st.Bytes += info.Size()
+		if err != nil {
+			return nil
+		}
+		if st.Oldest.IsZero() || m.Stored.Before(st.Oldest) {
+			st.Oldest = m.Stored
+		}
+		if m.Stored.After(st.Newest) {
+			st.Newest = m.Stored
+		}
+		return nil
+	})
+	return st, err
+}
+
+// Prune removes entries stored more than maxAge ago, along with unreadable entries and stale
+// temporary files. A zero maxAge removes every entry. It returns the number of entries and bytes removed.
+func (c *Cache) Prune(maxAge time.Duration) (int, int64, error) {
+	removed, freed := 0, int64(0)
+	err := c.walk(func(path string, info fs.FileInfo, m meta, err error) error {
+		if err == nil && maxAge > 0 && time.Since(m.Stored) <= maxAge {
+			return nil
+		}
+		if rmErr := os.Remove(path); rmErr != nil {
+			return rmErr
+		}
+		removed++
+		freed += info.Size()
+		return nil
+	})
+	return removed, freed, err
+}
+
+// walk calls fn for every file in the cache with its metadata, or the error reading it.
+func (c *Cache) walk(fn func(path string, info fs.FileInfo, m meta, err error) error) error {
+	return filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".tmp-") {
+			// An entry still being written, or left behind by a killed run; it ages from its last write.
+			return fn(path, info, meta{Stored: info.ModTime()}, nil)
+		}
+		m, metaErr := readMetaFile(path)
+		return fn(path, info, m, metaErr)
+	})
+}
+
+func readMetaFile(path string) (meta, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return meta{}, err
+	}
+	defer f.Close()
+	return readMeta(bufio.NewReader(f))
+}
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grumpzsux/goParams/internal/config"
+)
+
+// store writes an entry for key as if it had been stored age ago.
+func store(t *testing.T, c *Cache, key, body string, age time.Duration) {
+	t.Helper()
+	e, err := c.Create(key, http.StatusOK, http.Header{"Content-Type": {"text/plain"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Write([]byte(body))
+	if err := e.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if age == 0 {
+		return
+	}
+	// Rewrite the stored time in the entry's metadata line.
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m meta
+	line, rest, _ := bytes.Cut(data, []byte("\n"))
+	if err := json.Unmarshal(line, &m); err != nil {
+		t.Fatal(err)
+	}
+	m.Stored = time.Now().Add(-age).UTC()
+	line, _ = json.Marshal(m)
+	if err := os.WriteFile(path, append(append(line, '\n'), rest...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetTTL(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store(t, c, "https://example.com/fresh", "fresh body", 0)
+	store(t, c, "https://example.com/old", "old body", 3*time.Hour)
+
+	resp, ok := c.Get("https://example.com/fresh", time.Hour)
+	if !ok {
+		t.Fatal("fresh entry not served")
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "fresh body" || resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/plain" {
+		t.Errorf("fresh entry = %d %q %q", resp.StatusCode, resp.Header, body)
+	}
+
+	if resp, ok := c.Get("https://example.com/old", time.Hour); ok {
+		resp.Body.Close()
+		t.Error("entry older than the TTL was served")
+	}
+	resp, ok = c.Get("https://example.com/old", 4*time.Hour)
+	if !ok {
+		t.Fatal("entry within a longer TTL not served")
+	}
+	resp.Body.Close()
+	if _, ok := c.Get("https://example.com/missing", time.Hour); ok {
+		t.Error("missing entry served")
+	}
+}
+
+func TestKeyStripsSecrets(t *testing.T) {
+	got := Key("https://api.example.com/v2/domain/report?apikey=SECRET&domain=example.com&API_KEY=x")
+	if want := "https://api.example.com/v2/domain/report?domain=example.com"; got != want {
+		t.Errorf("Key = %q, want %q", got, want)
+	}
+	if got, want := Key("https://example.com/?a=1&b=2"), "https://example.com/?a=1&b=2"; got != want {
+		t.Errorf("Key without secrets = %q, want %q", got, want)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store(t, c, "https://example.com/new", "new", 0)
+	store(t, c, "https://example.com/day", "day", 25*time.Hour)
+	store(t, c, "https://example.com/week", "week", 8*24*time.Hour)
+	// An unreadable entry and a temporary file left by a killed run.
+	shard := filepath.Join(c.Dir(), "ab")
+	if err := os.MkdirAll(shard, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(shard, "garbage"), []byte("not an entry"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmp := filepath.Join(shard, ".tmp-123")
+	if err := os.WriteFile(tmp, []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-49 * time.Hour)
+	if err := os.Chtimes(tmp, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, freed, err := c.Prune(48 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 3 || freed <= 0 {
+		t.Errorf("Prune(48h) removed %d entries (%d bytes), want 3", removed, freed)
+	}
+	for key, want := range map[string]bool{
+		"https://example.com/new":  true,
+		"https://example.com/day":  true,
+		"https://example.com/week": false,
+	} {
+		if _, err := os.Stat(c.path(key)); (err == nil) != want {
+			t.Errorf("%s kept = %v, want %v", key, err == nil, want)
+		}
+	}
+
+	removed, _, err = c.Prune(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := c.Stats(); removed != 2 || st.Entries != 0 {
+		t.Errorf("Prune(0) removed %d entries leaving %d, want 2 leaving 0", removed, st.Entries)
+	}
+}
+
+// TestPruneDefaultsToLongestTTL prunes with the default age used by "cache prune", which must keep
+// entries that a source with a long per-source TTL still serves.
+func TestPruneDefaultsToLongestTTL(t *testing.T) {
+	cfg := &config.Config{
+		CacheTTL:  6 * time.Hour,
+		CacheTTLs: map[string]time.Duration{"wayback": 7 * 24 * time.Hour, "urlscan": time.Hour},
+	}
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store(t, c, "https://web.archive.org/cdx/search/cdx?url=example.com", "cdx", 3*24*time.Hour)
+	store(t, c, "https://example.com/expired", "expired", 8*24*time.Hour)
+
+	if _, _, err := c.Prune(cfg.MaxCacheTTL()); err != nil {
+		t.Fatal(err)
+	}
+	resp, ok := c.Get("https://web.archive.org/cdx/search/cdx?url=example.com", cfg.CacheTTLFor("wayback"))
+	if !ok {
+		t.Fatal("entry still within the wayback TTL was pruned")
+	}
+	resp.Body.Close()
+	if _, err := os.Stat(c.path("https://example.com/expired")); err == nil {
+		t.Error("entry older than every TTL was kept")
+	}
+}
@@ -0,0 +1,174 @@
+// Package checkpoint implements an append-only journal of completed source pages, so that an
+// interrupted harvest can be resumed without refetching the pages it already finished.
+package checkpoint
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// record is one line of the journal: a page of a source that finished for a domain.
+type record struct {
+	Domain  string          `json:"domain"`
+	Source  string          `json:"source"`
+	Page    string          `json:"page"`
+	Next    string          `json:"next,omitempty"` // Cursor of the following page, for cursor-paginated sources.
+	Results []result.Result `json:"results"`
+}
+
+// page is a completed page loaded from an earlier run.
+type page struct {
+	next    string
+	results []result.Result
+}
+
+// Journal records completed pages as JSON lines. Pages loaded when resuming are kept in memory so
+// that their URLs can be replayed; pages recorded during the current run are only written to disk.
+// It is safe for concurrent use.
+type Journal struct {
+	mu    sync.Mutex
+	path  string
+	f     *os.File
+	w     *bufio.Writer
+	pages map[string]page
+}
+
+// key identifies a page of a source for a domain.
+func key(domain, source, pageID string) string {
+	return domain + "\x00" + source + "\x00" + pageID
+}
+
+// Open opens the journal at path, creating its directory if needed. With resume, the pages already
+// in the journal are loaded and new pages are appended; otherwise any existing journal is discarded.
+// A truncated last line, as left by a killed process, is ignored.
+func Open(path string, resume bool) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	j := &Journal{path: path, pages: make(map[string]page)}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		if err := j.load(); err != nil {
+			return nil, err
+		}
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	j.f = f
+	j.w = bufio.NewWriter(f)
+	return j, nil
+}
+
+// load reads the pages recorded in an existing journal. An incomplete final line is cut off so
+// that new records are appended after the last complete one.
+func (j *Journal) load() error {
+	f, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var complete int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			var rec record
+			if jsonErr := json.Unmarshal(line, &rec); jsonErr != nil {
+				return fmt.Errorf("corrupt checkpoint %s: %w", j.path, jsonErr)
+			}
+			j.pages[key(rec.Domain, rec.Source, rec.Page)] = page{next: rec.Next, results: rec.Results}
+			complete += int64(len(line))
+			continue
+		}
+		if err == io.EOF {
+			if len(line) > 0 {
+				return os.Truncate(j.path, complete)
+			}
+			return nil
+		}
+		return err
+	}
+}
+
+// Loaded returns the number of completed pages loaded from an earlier run.
+func (j *Journal) Loaded() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.pages)
+}
+
+// Page returns the results and next cursor of a page completed by an earlier run.
+func (j *Journal) Page(domain, source, pageID string) ([]result.Result, string, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	p, ok := j.pages[key(domain, source, pageID)]
+	return p.results, p.next, ok
+}
+
+// Record appends a completed page to the journal and flushes it to disk.
+func (j *Journal) Record(domain, source, pageID string, results []result.Result, next string) error {
+	line, err := json.Marshal(record{Domain: domain, Source: source, Page: pageID, Next: next, Results: results})
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
+
+// Close flushes and closes the journal, keeping it on disk for a later resume.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.w.Flush(); err != nil {
+		j.f.Close()
+		return err
+	}
+	return j.f.Close()
+}
+
+// Remove closes the journal and deletes it, once a run has completed.
+func (j *Journal) Remove() error {
+	if err := j.Close(); err != nil {
+		return err
+	}
+	return os.Remove(j.path)
+}
+
+// Path returns the location of the journal.
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// DefaultPath returns the journal location for a run identified by parts (such as the targets and
+// selected sources): a file under $XDG_CACHE_HOME/goParams/checkpoints named after their hash, so
+// that rerunning the same command finds the same journal.
+func DefaultPath(parts ...string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return filepath.Join(dir, "goParams", "checkpoints", hex.EncodeToString(sum[:8])+".jsonl"), nil
+}
@@ -0,0 +1,114 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+func TestResumeAfterTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "journal.jsonl")
+	j, err := Open(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page0 := []result.Result{{URL: "https://example.com/?a=1", Sources: []string{"wayback"}}}
+	page1 := []result.Result{{URL: "https://example.com/?b=2", Sources: []string{"urlscan"}, Status: 200}}
+	if err := j.Record("example.com", "wayback", "page-0", page0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Record("example.com", "urlscan", "first", page1, "cursor-2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A process killed while writing leaves part of a line behind.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"domain":"example.com","source":"wayback","page":"page-1","results":[{"url":"https://exa`)
+	f.Close()
+
+	j, err = Open(path, true)
+	if err != nil {
+		t.Fatalf("Open with a torn last line: %v", err)
+	}
+	if n := j.Loaded(); n != 2 {
+		t.Errorf("loaded %d pages, want 2", n)
+	}
+	if results, next, ok := j.Page("example.com", "wayback", "page-0"); !ok || next != "" || !reflect.DeepEqual(results, page0) {
+		t.Errorf("page-0 = %v, %q, %v; want %v, \"\", true", results, next, ok, page0)
+	}
+	if results, next, ok := j.Page("example.com", "urlscan", "first"); !ok || next != "cursor-2" || !reflect.DeepEqual(results, page1) {
+		t.Errorf("urlscan first page = %v, %q, %v; want %v, \"cursor-2\", true", results, next, ok, page1)
+	}
+	// The torn page was never completed, so it is fetched again rather than replayed.
+	if _, _, ok := j.Page("example.com", "wayback", "page-1"); ok {
+		t.Error("torn page-1 was replayed")
+	}
+	if _, _, ok := j.Page("other.com", "wayback", "page-0"); ok {
+		t.Error("page of another domain was replayed")
+	}
+
+	// The refetched page is appended after the last complete line, leaving a readable journal.
+	page2 := []result.Result{{URL: "https://example.com/?c=3", Sources: []string{"wayback"}}}
+	if err := j.Record("example.com", "wayback", "page-1", page2, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	j, err = Open(path, true)
+	if err != nil {
+		t.Fatalf("reopening after the resumed run: %v", err)
+	}
+	defer j.Close()
+	if n := j.Loaded(); n != 3 {
+		t.Errorf("loaded %d pages after the resumed run, want 3", n)
+	}
+	if results, _, ok := j.Page("example.com", "wayback", "page-1"); !ok || !reflect.DeepEqual(results, page2) {
+		t.Errorf("page-1 = %v, %v; want %v, true", results, ok, page2)
+	}
+}
+
+func TestOpenWithoutResumeDiscards(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, err := Open(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Record("example.com", "wayback", "page-0", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	j, err = Open(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := j.Loaded(); n != 0 {
+		t.Errorf("loaded %d pages without resume, want 0", n)
+	}
+	if err := j.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("journal still exists after Remove: %v", err)
+	}
+}
+
+func TestResumeCorruptLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	if err := os.WriteFile(path, []byte("{not json}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path, true); err == nil {
+		t.Error("Open succeeded on a journal with a corrupt complete line")
+	}
+}
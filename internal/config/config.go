@@ -2,45 +2,289 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
 type Config struct {
-	VirusTotalAPIKey string   `yaml:"virustotal_api_key"`
-	AlienVaultAPIKey string   `yaml:"alienvault_api_key"`
+	VirusTotalAPIKey     string   `yaml:"virustotal_api_key"`
+	VirusTotalAPIKeyFile string   `yaml:"virustotal_api_key_file"` // File holding the VirusTotal API key, e.g. a mounted container secret.
+	AlienVaultAPIKey     string   `yaml:"alienvault_api_key"`
+	AlienVaultAPIKeyFile string   `yaml:"alienvault_api_key_file"` // File holding the AlienVault OTX API key.
+	VirusTotalAPIKeys    []string `yaml:"virustotal_api_keys"`     // Additional VirusTotal keys rotated with virustotal_api_key.
+	AlienVaultAPIKeys    []string `yaml:"alienvault_api_keys"`     // Additional AlienVault OTX keys rotated with alienvault_api_key.
+	URLScanAPIKey        string   `yaml:"urlscan_api_key"`         // Optional urlscan.io API key, for higher search limits.
+	URLScanAPIKeyFile    string   `yaml:"urlscan_api_key_file"`    // File holding the urlscan.io API key.
+	URLScanAPIKeys       []string `yaml:"urlscan_api_keys"`        // Additional urlscan.io keys rotated with urlscan_api_key.
 	// Additional configuration options:
-	Concurrency int      `yaml:"concurrency"`       // Number of concurrent requests.
-	UserAgents  []string `yaml:"user_agents"`       // Custom list of user-agent strings.
-	RateLimit   int      `yaml:"rate_limit"`        // Optional rate limit (requests per minute).
+	Concurrency       int            `yaml:"concurrency"`         // Number of concurrent requests.
+	UserAgents        []string       `yaml:"user_agents"`         // Custom list of user-agent strings.
+	RateLimit         int            `yaml:"rate_limit"`          // Optional rate limit (requests per minute).
+	RateLimits        map[string]int `yaml:"rate_limits"`         // Per-source rate limits (requests per minute), overriding RateLimit.
+	MaxAttempts       int            `yaml:"max_attempts"`        // Attempts per request before giving up (default 3).
+	SourceMaxAttempts map[string]int `yaml:"source_max_attempts"` // Per-source overrides for MaxAttempts.
+	CCIndexes         string         `yaml:"cc_indexes"`          // Common Crawl indexes to query: "N" newest, "all", a date range "from..to", or a list of IDs.
+	Scope             ScopeRules     `yaml:"scope"`               // Rules deciding which harvested URLs are kept.
+	IncludeSubdomains bool           `yaml:"include_subdomains"`  // Harvest every subdomain of each target (*.domain).
+	PublicSuffixList  string         `yaml:"public_suffix_list"`  // Optional path to a newer public_suffix_list.dat than the embedded copy.
+	Sources           []string       `yaml:"sources"`             // Sources to query (all registered sources if empty).
+	ExcludeSources    []string       `yaml:"exclude_sources"`     // Sources to skip.
+	LocalFiles        []string       `yaml:"local_files"`         // WARC, WAT, CDX or CDXJ files (or glob patterns) read by the local source.
+	ImportFiles       []string       `yaml:"import_files"`        // Burp XML, HAR or ZAP exports (or glob patterns) read by the import source.
+	MementoEndpoints  []string       `yaml:"memento_endpoints"`   // Memento TimeMap endpoints queried by the memento source; "{url}" marks where the URL pattern goes, otherwise it is appended.
+	// HTTP transport options:
+	Proxy               string                   `yaml:"proxy"`                   // HTTP(S) or SOCKS5 proxy URL, e.g. "http://127.0.0.1:8080" or "socks5://127.0.0.1:1080".
+	Proxies             []string                 `yaml:"proxies"`                 // Proxy URLs rotated round-robin across requests, together with Proxy.
+	ProxyList           string                   `yaml:"proxy_list"`              // File with one proxy URL per line, added to Proxies.
+	CABundle            string                   `yaml:"ca_bundle"`               // PEM file with extra CA certificates to trust, e.g. an intercepting proxy's CA.
+	InsecureSkipVerify  bool                     `yaml:"insecure_skip_verify"`    // Skip TLS certificate verification.
+	MaxIdleConns        int                      `yaml:"max_idle_conns"`          // Idle connections kept across all hosts (default 100).
+	MaxIdleConnsPerHost int                      `yaml:"max_idle_conns_per_host"` // Idle connections kept per host (default 10).
+	MaxConnsPerHost     int                      `yaml:"max_conns_per_host"`      // Connections per host, including active ones; zero means unlimited.
+	Timeout             time.Duration            `yaml:"timeout"`                 // Timeout of a single request attempt, e.g. "30s".
+	Timeouts            map[string]time.Duration `yaml:"timeouts"`                // Per-source overrides for Timeout.
+	// Response cache options:
+	NoCache   bool                     `yaml:"no_cache"`   // Disable the response cache.
+	CacheDir  string                   `yaml:"cache_dir"`  // Cache location (default $XDG_CACHE_HOME/goParams/responses).
+	CacheTTL  time.Duration            `yaml:"cache_ttl"`  // How long cached responses are reused (default 24h).
+	CacheTTLs map[string]time.Duration `yaml:"cache_ttls"` // Per-source overrides for CacheTTL; zero disables caching for the source.
+	StateDir  string                   `yaml:"state_dir"`  // Where --new-only keeps the URLs seen per target (default $XDG_STATE_HOME/goParams).
 	// You can add more fields as needed.
+
+	Files []string `yaml:"-"` // Configuration files that were loaded, lowest precedence first.
 }
 
-// LoadConfig reads a YAML configuration file.
-func LoadConfig(path string) (*Config, error) {
-	if path == "" {
-		path = "config.yaml"
+// ScopeRules describes which hosts and paths are in scope. The same shape is accepted in a standalone scope file.
+type ScopeRules struct {
+	Include      []string `yaml:"include"`       // Hosts in scope: "example.com", "*.example.com" or a CIDR range. Defaults to the target.
+	Exclude      []string `yaml:"exclude"`       // Hosts removed from scope, using the same patterns as Include.
+	IncludePaths []string `yaml:"include_paths"` // If set, only URLs whose path starts with one of these prefixes are kept.
+	ExcludePaths []string `yaml:"exclude_paths"` // URLs whose path starts with one of these prefixes are dropped.
+	ExcludeRegex []string `yaml:"exclude_regex"` // URLs matching any of these regular expressions are dropped.
+}
+
+// Credential keys that sources may declare as required, or use when they are set.
+const (
+	KeyVirusTotalAPIKey = "virustotal_api_key"
+	KeyAlienVaultAPIKey = "alienvault_api_key"
+	KeyURLScanAPIKey    = "urlscan_api_key"
+)
+
+// Credential returns the value of the credential identified by key, or an empty string if it is not set.
+// For credentials with a key pool, the first key of the pool is returned.
+func (c *Config) Credential(key string) string {
+	if pool := c.CredentialPool(key); len(pool) > 0 {
+		return pool[0]
 	}
-	data, err := os.ReadFile(path)
+	return ""
+}
+
+// CredentialPool returns every configured value of the credential identified by key: the single key
+// followed by the keys of its *_keys list, without blanks or duplicates.
+func (c *Config) CredentialPool(key string) []string {
+	var values []string
+	switch strings.ToLower(key) {
+	case KeyVirusTotalAPIKey:
+		values = append([]string{c.VirusTotalAPIKey}, c.VirusTotalAPIKeys...)
+	case KeyAlienVaultAPIKey:
+		values = append([]string{c.AlienVaultAPIKey}, c.AlienVaultAPIKeys...)
+	case KeyURLScanAPIKey:
+		values = append([]string{c.URLScanAPIKey}, c.URLScanAPIKeys...)
+	}
+	var pool []string
+	seen := make(map[string]struct{})
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if _, dup := seen[v]; v == "" || dup {
+			continue
+		}
+		seen[v] = struct{}{}
+		pool = append(pool, v)
+	}
+	return pool
+}
+
+// RateLimitFor returns the rate limit (requests per minute) for the named source.
+// A per-source override takes precedence over the global rate_limit; zero means unlimited.
+func (c *Config) RateLimitFor(source string) int {
+	if limit, ok := c.RateLimits[strings.ToLower(source)]; ok {
+		return limit
+	}
+	return c.RateLimit
+}
+
+// DefaultMaxAttempts is used when neither max_attempts nor a per-source override is set.
+const DefaultMaxAttempts = 3
+
+// MaxAttemptsFor returns how many times a request from the named source may be attempted.
+// A per-source override takes precedence over the global max_attempts.
+func (c *Config) MaxAttemptsFor(source string) int {
+	if attempts, ok := c.SourceMaxAttempts[strings.ToLower(source)]; ok && attempts > 0 {
+		return attempts
+	}
+	if c.MaxAttempts > 0 {
+		return c.MaxAttempts
+	}
+	return DefaultMaxAttempts
+}
+
+// DefaultTimeout bounds a single request attempt when neither timeout nor a per-source override is set.
+const DefaultTimeout = 15 * time.Second
+
+// defaultSourceTimeouts are built-in timeouts for sources whose responses routinely take longer than
+// DefaultTimeout. The Wayback Machine streams large CDX pages, and Memento archives build whole
+// TimeMaps before answering.
+var defaultSourceTimeouts = map[string]time.Duration{
+	"wayback": 2 * time.Minute,
+	"memento": 2 * time.Minute,
+}
+
+// TimeoutFor returns the timeout of a single request attempt made by the named source.
+// A per-source override takes precedence over the global timeout, which takes precedence over
+// the built-in defaults.
+func (c *Config) TimeoutFor(source string) time.Duration {
+	source = strings.ToLower(source)
+	if timeout, ok := c.Timeouts[source]; ok && timeout > 0 {
+		return timeout
+	}
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	if timeout, ok := defaultSourceTimeouts[source]; ok {
+		return timeout
+	}
+	return DefaultTimeout
+}
+
+// DefaultCacheTTL is how long cached responses are reused when neither cache_ttl nor a per-source
+// override is set.
+const DefaultCacheTTL = 24 * time.Hour
+
+// CacheTTLFor returns how long responses of the named source may be served from the cache.
+// A per-source override, which may be zero to disable caching, takes precedence over cache_ttl.
+func (c *Config) CacheTTLFor(source string) time.Duration {
+	if c.NoCache {
+		return 0
+	}
+	if ttl, ok := c.CacheTTLs[strings.ToLower(source)]; ok {
+		return ttl
+	}
+	if c.CacheTTL > 0 {
+		return c.CacheTTL
+	}
+	return DefaultCacheTTL
+}
+
+// MaxCacheTTL returns the longest time that responses of any source may be served from the cache:
+// the largest of cache_ttl (or its default) and the per-source overrides. NoCache is ignored.
+func (c *Config) MaxCacheTTL() time.Duration {
+	ttl := c.CacheTTL
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	for _, sourceTTL := range c.CacheTTLs {
+		if sourceTTL > ttl {
+			ttl = sourceTTL
+		}
+	}
+	return ttl
+}
+
+// ProxyURLs returns every configured proxy: Proxy, then Proxies, then the entries of the ProxyList
+// file. Blank lines and lines starting with "#" in the file are ignored.
+func (c *Config) ProxyURLs() ([]string, error) {
+	var proxies []string
+	if c.Proxy != "" {
+		proxies = append(proxies, c.Proxy)
+	}
+	proxies = append(proxies, c.Proxies...)
+	if c.ProxyList != "" {
+		data, err := os.ReadFile(c.ProxyList)
+		if err != nil {
+			return nil, fmt.Errorf("proxy_list: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				proxies = append(proxies, line)
+			}
+		}
+	}
+	return proxies, nil
+}
+
+// DefaultConfigFile is the project configuration file read from the current directory.
+const DefaultConfigFile = "config.yaml"
+
+// UserConfigPath returns the per-user configuration file,
+// $XDG_CONFIG_HOME/goParams/config.yaml (~/.config/goParams/config.yaml if unset).
+func UserConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
 	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goParams", "config.yaml"), nil
+}
+
+// LoadConfig builds the configuration from its layers, each overriding the previous one:
+// the per-user file (see UserConfigPath), the project file, then GOPARAMS_* environment variables.
+// The project file is path if given, which must exist, or DefaultConfigFile if present.
+// Secrets set through a *_file key are read from that file. Defaults are filled in by Validate
+// and command-line flags are applied by the caller.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+
+	if userPath, err := UserConfigPath(); err == nil {
+		if err := cfg.mergeFile(userPath, false); err != nil {
+			return nil, err
+		}
+	}
+	if path != "" {
+		if err := cfg.mergeFile(path, true); err != nil {
+			return nil, err
+		}
+	} else if err := cfg.mergeFile(DefaultConfigFile, false); err != nil {
 		return nil, err
 	}
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if err := cfg.mergeEnv(os.LookupEnv); err != nil {
 		return nil, err
 	}
-	return &cfg, nil
+	return cfg, nil
 }
 
-// Validate checks that required configuration fields are set.
-func Validate(cfg *Config) error {
-	if cfg.VirusTotalAPIKey == "" {
-		return errors.New("virus total api key is missing")
+// mergeFile applies the YAML file at path on top of c. A missing file is skipped unless required.
+func (c *Config) mergeFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !required && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	// Decode into an empty layer first to learn which secrets this file sets, then on top of c so
+	// that only the keys present in the file override earlier layers.
+	var layer Config
+	if err := yaml.Unmarshal(data, &layer); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
 	}
-	if cfg.AlienVaultAPIKey == "" {
-		return errors.New("alien vault api key is missing")
+	if err := c.resolveSecrets(&layer); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
 	}
+	c.Files = append(c.Files, path)
+	return nil
+}
+
+// Validate checks the configuration and fills in defaults for unset fields.
+// API keys are optional: sources whose credentials are missing are disabled individually.
+func Validate(cfg *Config) error {
 	if cfg.Concurrency <= 0 {
 		// Set a default value if not provided.
 		cfg.Concurrency = 5
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheTTLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		source  string
+		wantFor time.Duration
+		wantMax time.Duration
+	}{
+		{"defaults", Config{}, "wayback", DefaultCacheTTL, DefaultCacheTTL},
+		{"global", Config{CacheTTL: 6 * time.Hour}, "wayback", 6 * time.Hour, 6 * time.Hour},
+		{"longer override", Config{CacheTTL: 6 * time.Hour, CacheTTLs: map[string]time.Duration{"wayback": 7 * 24 * time.Hour}}, "wayback", 7 * 24 * time.Hour, 7 * 24 * time.Hour},
+		{"shorter override", Config{CacheTTLs: map[string]time.Duration{"urlscan": time.Hour}}, "urlscan", time.Hour, DefaultCacheTTL},
+		{"other source", Config{CacheTTLs: map[string]time.Duration{"urlscan": time.Hour}}, "wayback", DefaultCacheTTL, DefaultCacheTTL},
+		{"disabled source", Config{CacheTTLs: map[string]time.Duration{"urlscan": 0}}, "urlscan", 0, DefaultCacheTTL},
+		{"source names are case-insensitive", Config{CacheTTLs: map[string]time.Duration{"wayback": 2 * time.Hour}}, "Wayback", 2 * time.Hour, DefaultCacheTTL},
+		// NoCache turns off lookups but does not change how long entries are worth keeping.
+		{"no cache", Config{NoCache: true, CacheTTLs: map[string]time.Duration{"wayback": 48 * time.Hour}}, "wayback", 0, 48 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.CacheTTLFor(tt.source); got != tt.wantFor {
+			t.Errorf("%s: CacheTTLFor(%q) = %v, want %v", tt.name, tt.source, got, tt.wantFor)
+		}
+		if got := tt.cfg.MaxCacheTTL(); got != tt.wantMax {
+			t.Errorf("%s: MaxCacheTTL() = %v, want %v", tt.name, got, tt.wantMax)
+		}
+	}
+}
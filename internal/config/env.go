@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix is prepended to the upper-cased YAML key to form the environment variable that
+// overrides it, e.g. GOPARAMS_VIRUSTOTAL_API_KEY or GOPARAMS_CONCURRENCY.
+const EnvPrefix = "GOPARAMS_"
+
+// secret describes a credential that may be given directly or read from a file through its *_file key,
+// plus the pool of additional keys rotated with it.
+type secret struct {
+	key   string
+	value func(*Config) *string
+	file  func(*Config) *string
+	pool  func(*Config) *[]string
+}
+
+var secrets = []secret{
+	{
+		key:   KeyVirusTotalAPIKey,
+		value: func(c *Config) *string { return &c.VirusTotalAPIKey },
+		file:  func(c *Config) *string { return &c.VirusTotalAPIKeyFile },
+		pool:  func(c *Config) *[]string { return &c.VirusTotalAPIKeys },
+	},
+	{
+		key:   KeyAlienVaultAPIKey,
+		value: func(c *Config) *string { return &c.AlienVaultAPIKey },
+		file:  func(c *Config) *string { return &c.AlienVaultAPIKeyFile },
+		pool:  func(c *Config) *[]string { return &c.AlienVaultAPIKeys },
+	},
+	{
+		key:   KeyURLScanAPIKey,
+		value: func(c *Config) *string { return &c.URLScanAPIKey },
+		file:  func(c *Config) *string { return &c.URLScanAPIKeyFile },
+		pool:  func(c *Config) *[]string { return &c.URLScanAPIKeys },
+	},
+}
+
+// resolveSecrets applies the secrets set by layer, which has already been merged into c.
+// A *_file key reads the secret from that file; a direct value overrides a file set by an earlier layer.
+func (c *Config) resolveSecrets(layer *Config) error {
+	for _, s := range secrets {
+		if path := *s.file(layer); path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("%s_file: %w", s.key, err)
+			}
+			*s.value(c) = strings.TrimSpace(string(data))
+		} else if *s.value(layer) != "" {
+			*s.file(c) = ""
+		}
+	}
+	return nil
+}
+
+// mergeEnv applies the GOPARAMS_* environment variables on top of c. Variables exist for every
+// top-level string, number, boolean, duration and list key; lists are comma-separated.
+func (c *Config) mergeEnv(lookup func(string) (string, bool)) error {
+	var layer Config
+	lv := reflect.ValueOf(&layer).Elem()
+	cv := reflect.ValueOf(c).Elem()
+	t := lv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := yamlKey(t.Field(i))
+		if key == "" {
+			continue
+		}
+		name := EnvPrefix + strings.ToUpper(key)
+		raw, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := setFromString(lv.Field(i), raw); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		cv.Field(i).Set(lv.Field(i))
+	}
+	return c.resolveSecrets(&layer)
+}
+
+// yamlKey returns the YAML key of a Config field, or an empty string if it cannot be set from the environment.
+func yamlKey(f reflect.StructField) string {
+	key := strings.Split(f.Tag.Get("yaml"), ",")[0]
+	if key == "" || key == "-" {
+		return ""
+	}
+	if f.Type == durationType {
+		return key
+	}
+	switch f.Type.Kind() {
+	case reflect.String, reflect.Int, reflect.Bool:
+		return key
+	case reflect.Slice:
+		if f.Type.Elem().Kind() == reflect.String {
+			return key
+		}
+	}
+	return ""
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setFromString parses raw into v according to its kind. Durations use time.ParseDuration syntax.
+func setFromString(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	}
+	return nil
+}
+
+// Redacted returns a copy of c with every secret replaced by a placeholder, suitable for display.
+func (c *Config) Redacted() *Config {
+	out := *c
+	for _, s := range secrets {
+		if *s.value(&out) != "" {
+			*s.value(&out) = "<redacted>"
+		}
+		if pool := *s.pool(&out); len(pool) > 0 {
+			redacted := make([]string, len(pool))
+			for i := range redacted {
+				redacted[i] = "<redacted>"
+			}
+			*s.pool(&out) = redacted
+		}
+	}
+	return &out
+}
@@ -0,0 +1,121 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// bodyParams returns the names of the parameters sent in a request body: the fields of an
+// application/x-www-form-urlencoded or multipart/form-data body, or the keys of a JSON object, with
+// nested object keys written in bracket notation ("user[name]"). Other bodies yield no names.
+func bodyParams(contentType string, body []byte) []string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return formParams(body)
+	case mediaType == "multipart/form-data":
+		return multipartParams(body, params["boundary"])
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return jsonParams(body)
+	case mediaType == "":
+		// Recorders do not always keep the Content-Type; recognise the common shapes.
+		trimmed := bytes.TrimSpace(body)
+		if bytes.HasPrefix(trimmed, []byte("{")) {
+			return jsonParams(trimmed)
+		}
+		return formParams(trimmed)
+	}
+	return nil
+}
+
+func formParams(body []byte) []string {
+	values, err := url.ParseQuery(strings.TrimSpace(string(body)))
+	if err != nil && len(values) == 0 {
+		return nil
+	}
+	var names []string
+	for name := range values {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func multipartParams(body []byte, boundary string) []string {
+	if boundary == "" {
+		return nil
+	}
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	seen := make(map[string]struct{})
+	var names []string
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			// io.EOF ends the body; a truncated body still yields the parts read so far.
+			break
+		}
+		if name := part.FormName(); name != "" {
+			if _, dup := seen[name]; !dup {
+				seen[name] = struct{}{}
+				names = append(names, name)
+			}
+		}
+		part.Close()
+	}
+	return names
+}
+
+func jsonParams(body []byte) []string {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil
+	}
+	var names []string
+	var walk func(prefix string, obj map[string]interface{})
+	walk = func(prefix string, obj map[string]interface{}) {
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			name := k
+			if prefix != "" {
+				name = prefix + "[" + k + "]"
+			}
+			if nested, ok := obj[k].(map[string]interface{}); ok && len(nested) > 0 {
+				walk(name, nested)
+				continue
+			}
+			names = append(names, name)
+		}
+	}
+	walk("", doc)
+	return names
+}
+
+// addParams adds each name that is not already in q with an empty value, which the cleaning step
+// replaces with the placeholder like any other value, and returns the names it added.
+func addParams(q url.Values, names []string) []string {
+	var added []string
+	for _, name := range names {
+		if _, ok := q[name]; !ok {
+			q.Set(name, "")
+			added = append(added, name)
+		}
+	}
+	return added
+}
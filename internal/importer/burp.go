@@ -0,0 +1,82 @@
+package importer
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// burpItem is an <item> of a Burp Suite "Save items" export.
+type burpItem struct {
+	Time     string   `xml:"time"`
+	URL      string   `xml:"url"`
+	Request  burpData `xml:"request"`
+	Status   string   `xml:"status"`
+	MIMEType string   `xml:"mimetype"`
+}
+
+// burpData is a raw request or response, base64-encoded if the export was saved that way.
+type burpData struct {
+	Base64 bool   `xml:"base64,attr"`
+	Data   string `xml:",chardata"`
+}
+
+func (d burpData) bytes() []byte {
+	if !d.Base64 {
+		return []byte(d.Data)
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(d.Data))
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+// burpMIMETypes maps the MIME type names Burp records to media types.
+var burpMIMETypes = map[string]string{
+	"html":   "text/html",
+	"json":   "application/json",
+	"xml":    "text/xml",
+	"script": "application/javascript",
+	"css":    "text/css",
+	"text":   "text/plain",
+}
+
+// readBurp streams the items of a Burp Suite XML export, one at a time.
+func readBurp(r io.Reader, fn func(request) bool) error {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "item" {
+			continue
+		}
+		var item burpItem
+		if err := dec.DecodeElement(&item, &start); err != nil {
+			return err
+		}
+		contentType, body := parseRawMessage(item.Request.bytes())
+		req := request{
+			url:         strings.TrimSpace(item.URL),
+			contentType: contentType,
+			body:        body,
+			status:      result.ParseStatus(item.Status),
+			mime:        burpMIMETypes[strings.ToLower(item.MIMEType)],
+		}
+		req.time, _ = time.Parse(time.UnixDate, strings.TrimSpace(item.Time))
+		if !fn(req) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package importer
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// harLog is the part of a HAR 1.2 archive that goParams uses.
+type harLog struct {
+	Log struct {
+		Entries []struct {
+			StartedDateTime string `json:"startedDateTime"`
+			Request         struct {
+				URL      string `json:"url"`
+				PostData *struct {
+					MimeType string    `json:"mimeType"`
+					Params   []harPair `json:"params"`
+					Text     string    `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+			Response struct {
+				Status  int `json:"status"`
+				Content struct {
+					MimeType string `json:"mimeType"`
+				} `json:"content"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+type harPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// readHAR reads the entries of a HAR archive. Posted form parameters listed in postData.params are
+// used as is; otherwise postData.text is parsed according to its MIME type.
+func readHAR(r io.Reader, fn func(request) bool) error {
+	var har harLog
+	if err := json.NewDecoder(r).Decode(&har); err != nil {
+		return err
+	}
+	for _, entry := range har.Log.Entries {
+		req := request{
+			url:    entry.Request.URL,
+			status: entry.Response.Status,
+			mime:   result.MediaType(entry.Response.Content.MimeType),
+			time:   result.ParseTimestamp(entry.StartedDateTime),
+		}
+		if post := entry.Request.PostData; post != nil {
+			req.contentType = post.MimeType
+			if len(post.Params) > 0 {
+				// Encode the listed fields as a form body so they go through the same extraction.
+				form := url.Values{}
+				for _, p := range post.Params {
+					form.Add(p.Name, p.Value)
+				}
+				req.contentType, req.body = "application/x-www-form-urlencoded", []byte(form.Encode())
+			} else {
+				req.body = []byte(post.Text)
+			}
+		}
+		if !fn(req) {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// Package importer reads the requests recorded by intercepting proxies during manual testing: Burp
+// Suite saved items (XML), HAR 1.2 archives exported by browsers and proxies, and ZAP message exports.
+// Parameters sent in request bodies are merged into the query string of each URL, so that POST-only
+// parameters reach the same cleaning and wordlist pipeline as query parameters, and are listed in the
+// Result's BodyParams.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// Format identifies the layout of an import file.
+type Format string
+
+const (
+	FormatBurp Format = "burp" // Burp Suite "Save items" XML.
+	FormatHAR  Format = "har"  // HTTP Archive 1.2 JSON.
+	FormatZAP  Format = "zap"  // ZAP "Export Messages to File" text.
+)
+
+// request is a recorded request and what is known about its response.
+type request struct {
+	url         string
+	contentType string
+	body        []byte
+	status      int
+	mime        string
+	time        time.Time
+}
+
+// result converts the request to a Result whose URL also carries the parameters of its body. The
+// parameters that only the body carried are listed in BodyParams.
+func (r request) result() (result.Result, bool) {
+	u, err := url.Parse(r.url)
+	if err != nil || u.Host == "" {
+		return result.Result{}, false
+	}
+	var added []string
+	if names := bodyParams(r.contentType, r.body); len(names) > 0 {
+		q := u.Query()
+		added = addParams(q, names)
+		u.RawQuery = q.Encode()
+	}
+	res := result.Result{URL: u.String(), Status: r.status, MIME: r.mime}
+	for _, name := range added {
+		res.AddBodyParam(name)
+	}
+	res.SetSeen(r.time)
+	return res, true
+}
+
+// ReadFile streams the requests recorded in the file at path to fn as Results, until the file ends
+// or fn returns false. The format is detected from the content.
+func ReadFile(path string, fn func(result.Result) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, 64*1024)
+	format, err := detect(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	emit := func(req request) bool {
+		res, ok := req.result()
+		if !ok {
+			return true
+		}
+		return fn(res)
+	}
+	switch format {
+	case FormatBurp:
+		err = readBurp(r, emit)
+	case FormatHAR:
+		err = readHAR(r, emit)
+	default:
+		err = readZAP(r, emit)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// detect sniffs the format of r without consuming it.
+func detect(r *bufio.Reader) (Format, error) {
+	head, err := r.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", err
+	}
+	head = bytes.TrimLeft(head, "\ufeff \t\r\n")
+	switch {
+	case bytes.HasPrefix(head, []byte("<")):
+		return FormatBurp, nil
+	case bytes.HasPrefix(head, []byte("{")):
+		return FormatHAR, nil
+	case bytes.HasPrefix(head, []byte("===")):
+		return FormatZAP, nil
+	}
+	return "", fmt.Errorf("unrecognised import format (expected Burp XML, HAR or a ZAP message export)")
+}
+
+// parseRawMessage returns the Content-Type header and body of a raw HTTP message as captured by a
+// proxy. It is lenient about line endings and the HTTP version, which may be HTTP/2.
+func parseRawMessage(raw []byte) (contentType string, body []byte) {
+	head, body := raw, []byte(nil)
+	if i := bytes.Index(raw, []byte("\r\n\r\n")); i >= 0 {
+		head, body = raw[:i], raw[i+4:]
+	} else if i := bytes.Index(raw, []byte("\n\n")); i >= 0 {
+		head, body = raw[:i], raw[i+2:]
+	}
+	for _, line := range bytes.Split(head, []byte("\n")) {
+		name, value, ok := bytes.Cut(line, []byte(":"))
+		if ok && strings.EqualFold(string(bytes.TrimSpace(name)), "Content-Type") {
+			contentType = string(bytes.TrimSpace(value))
+		}
+	}
+	return contentType, body
+}
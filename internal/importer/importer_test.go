@@ -0,0 +1,90 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// readAll reads the file at path and describes each result as
+// "URL status MIME capture-time body:<body params>".
+func readAll(t *testing.T, path string) []string {
+	t.Helper()
+	var got []string
+	err := ReadFile(path, func(r result.Result) bool {
+		seen := "-"
+		if r.LastSeen != nil {
+			seen = r.LastSeen.UTC().Format(time.RFC3339)
+		}
+		got = append(got, fmt.Sprintf("%s %d %q %s body:%s", r.URL, r.Status, r.MIME, seen, strings.Join(r.BodyParams, ",")))
+		return true
+	})
+	if err != nil {
+		t.Fatalf("ReadFile(%s): %v", path, err)
+	}
+	return got
+}
+
+func TestReadFileFormats(t *testing.T) {
+	tests := []struct {
+		file string
+		want []string
+	}{
+		// Base64-encoded requests are decoded, and form fields not already in the query are added.
+		{"burp.xml", []string{
+			`https://example.com/search?q=shoes 200 "text/html" 2024-05-01T10:00:00Z body:`,
+			`https://example.com/login?next=%2F&pass=&user= 302 "" 2024-05-01T10:01:00Z body:pass,user`,
+		}},
+		// Listed form params, nested JSON keys in bracket notation, and entries without an HTTP host dropped.
+		{"sample.har", []string{
+			`https://example.com/search?q=shoes 200 "text/html" 2024-05-01T10:00:00Z body:`,
+			`https://example.com/login?pass=&user= 302 "" 2024-05-01T10:01:00Z body:pass,user`,
+			`https://api.example.com/v1/items?filter%5Bprice%5D%5Bmax%5D=&filter%5Btag%5D=&page=2 201 "application/json" 2024-05-01T10:02:00Z body:filter[price][max],filter[tag]`,
+		}},
+		// Multipart fields are taken by name, and the response follows the body's Content-Length.
+		{"zap.txt", []string{
+			`https://example.com/search?q=shoes 200 "text/html" - body:`,
+			`https://example.com/upload?file=&title= 413 "application/json" - body:file,title`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got := readAll(t, filepath.Join("testdata", tt.file))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("results:\n\t%s\nwant:\n\t%s", strings.Join(got, "\n\t"), strings.Join(tt.want, "\n\t"))
+			}
+		})
+	}
+}
+
+func TestReadFileStops(t *testing.T) {
+	for _, file := range []string{"burp.xml", "sample.har", "zap.txt"} {
+		n := 0
+		err := ReadFile(filepath.Join("testdata", file), func(result.Result) bool {
+			n++
+			return false
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if n != 1 {
+			t.Errorf("%s: fn called %d times after asking to stop, want 1", file, n)
+		}
+	}
+}
+
+func TestReadFileUnrecognised(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("GET / HTTP/1.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReadFile(path, func(result.Result) bool { return true }); err == nil {
+		t.Error("ReadFile succeeded on a file that is not a proxy export")
+	}
+}
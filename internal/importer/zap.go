@@ -0,0 +1,104 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// zapSeparator starts each message of a ZAP message export: "==== 12 ==========".
+var zapSeparator = regexp.MustCompile(`^==== \d+ =+\s*$`)
+
+// readZAP reads a ZAP "Export Messages to File" text export, in which each message is the raw
+// request (with an absolute URL in its request line) followed by the raw response.
+func readZAP(r *bufio.Reader, fn func(request) bool) error {
+	var block bytes.Buffer
+	flush := func() bool {
+		defer block.Reset()
+		if req, ok := parseZAPMessage(block.Bytes()); ok {
+			return fn(req)
+		}
+		return true
+	}
+	for {
+		line, err := r.ReadBytes('\n')
+		if zapSeparator.Match(bytes.TrimRight(line, "\r\n")) {
+			if !flush() {
+				return nil
+			}
+		} else {
+			block.Write(line)
+		}
+		if err == io.EOF {
+			flush()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseZAPMessage extracts the request of one exported message and the status and media type of
+// its response.
+func parseZAPMessage(msg []byte) (request, bool) {
+	msg = bytes.TrimLeft(msg, "\r\n")
+	requestLine, _, _ := bytes.Cut(msg, []byte("\n"))
+	fields := strings.Fields(string(requestLine))
+	if len(fields) < 2 {
+		return request{}, false
+	}
+	req := request{url: fields[1]}
+
+	headEnd, sepLen := bytes.Index(msg, []byte("\r\n\r\n")), 4
+	if headEnd < 0 {
+		headEnd, sepLen = bytes.Index(msg, []byte("\n\n")), 2
+	}
+	if headEnd < 0 {
+		return req, true
+	}
+	head, rest := msg[:headEnd], msg[headEnd+sepLen:]
+	length := -1
+	for _, line := range bytes.Split(head, []byte("\n")) {
+		name, value, ok := bytes.Cut(line, []byte(":"))
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(string(bytes.TrimSpace(name))) {
+		case "content-type":
+			req.contentType = string(bytes.TrimSpace(value))
+		case "content-length":
+			if n, err := strconv.Atoi(string(bytes.TrimSpace(value))); err == nil {
+				length = n
+			}
+		}
+	}
+
+	// The body runs for Content-Length bytes, or otherwise up to the response's status line.
+	var response []byte
+	switch {
+	case length >= 0 && length <= len(rest):
+		req.body, response = rest[:length], rest[length:]
+	default:
+		if i := bytes.Index(rest, []byte("HTTP/")); i >= 0 && (i == 0 || rest[i-1] == '\n') {
+			req.body, response = rest[:i], rest[i:]
+		} else {
+			req.body = rest
+		}
+	}
+
+	response = bytes.TrimLeft(response, "\r\n")
+	if statusLine, responseRest, ok := bytes.Cut(response, []byte("\n")); ok && bytes.HasPrefix(statusLine, []byte("HTTP/")) {
+		if parts := strings.Fields(string(statusLine)); len(parts) >= 2 {
+			req.status = result.ParseStatus(parts[1])
+		}
+		contentType, _ := parseRawMessage(responseRest)
+		req.mime = result.MediaType(contentType)
+	}
+	return req, true
+}
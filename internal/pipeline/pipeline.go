@@ -0,0 +1,146 @@
+package pipeline
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/grumpzsux/goParams/internal/api"
+	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/psl"
+	"github.com/grumpzsux/goParams/internal/result"
+	"github.com/grumpzsux/goParams/internal/scope"
+	"github.com/grumpzsux/goParams/internal/utils"
+)
+
+// Options controls how harvested URLs are processed before they are written.
+type Options struct {
+	Extensions  []string       // File extensions to skip.
+	Placeholder string         // Canary placeholder for URL query parameter values.
+	SmartDedupe bool           // Collapse URLs that share a pattern (see utils.PatternKey), keeping one representative.
+	Scope       *scope.Scope   // If set, URLs outside the scope are dropped before cleaning, and crawling sources only follow URLs in scope.
+	Checkpoint  api.Checkpoint // If set, completed source pages are recorded, and pages it already holds are replayed instead of fetched.
+}
+
+// Run harvests URLs for every domain and streams them through cleaning and deduplication to w.
+// Domains are processed concurrently, bounded by cfg.Concurrency. Run returns once all domains are done,
+// along with a per-source summary of URL counts and errors.
+func Run(ctx context.Context, domains []string, cfg *config.Config, opts Options, w utils.ResultWriter) *api.Summary {
+	summary := api.NewSummary()
+	ctx = api.WithSummary(ctx, summary)
+	ctx = api.WithTargets(ctx, domains)
+	if opts.Checkpoint != nil {
+		ctx = api.WithCheckpoint(ctx, opts.Checkpoint)
+	}
+	if opts.Scope != nil {
+		ctx = api.WithScope(ctx, opts.Scope)
+	}
+
+	// Create a semaphore channel for dynamic concurrency.
+	sem := make(chan struct{}, cfg.Concurrency)
+	var wg sync.WaitGroup
+
+	for _, d := range domains {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(target string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			processDomain(ctx, target, cfg, opts, w)
+		}(d)
+	}
+	wg.Wait()
+	return summary
+}
+
+// processDomain queries all selected sources for target, writing each new cleaned URL as soon as it arrives.
+// Results that clean to the same URL are merged, and the merged set is handed to w when the domain finishes.
+func processDomain(ctx context.Context, target string, cfg *config.Config, opts Options, w utils.ResultWriter) {
+	logrus.Infof("Processing domain: %s", target)
+
+	rawCh := make(chan result.Result, 256)
+	go func() {
+		defer close(rawCh)
+		if err := api.FetchAll(ctx, target, cfg, rawCh); err != nil {
+			logrus.Errorf("Error fetching URLs for %s: %v", target, err)
+		}
+	}()
+
+	// Only the cleaned URLs and their merged metadata are kept, which is what deduplication needs.
+	// Results are keyed by cleaned URL, or by URL pattern in smart-dedupe mode; a pattern's
+	// representative only merges metadata from captures of its own URL.
+	seen := make(map[string]*result.Result)
+	absorbed := make(map[string]struct{})
+	var ordered []*result.Result
+	outOfScope := 0
+	for raw := range rawCh {
+		if opts.Scope != nil && !opts.Scope.InScope(raw.URL, target) {
+			outOfScope++
+			continue
+		}
+		cleaned, ok := utils.CleanURLString(raw.URL, opts.Extensions, opts.Placeholder)
+		if !ok {
+			continue
+		}
+		key := cleaned
+		if opts.SmartDedupe {
+			key = utils.PatternKey(cleaned)
+		}
+		if existing, dup := seen[key]; dup {
+			if existing.URL == cleaned {
+				existing.Merge(raw)
+			} else if _, counted := absorbed[cleaned]; !counted {
+				absorbed[cleaned] = struct{}{}
+				existing.Absorbed++
+			}
+			continue
+		}
+		r := &result.Result{URL: cleaned, Host: hostOf(cleaned)}
+		r.Merge(raw)
+		seen[key] = r
+		ordered = append(ordered, r)
+		if err := w.Write(target, r); err != nil {
+			logrus.Errorf("Failed to write result for %s: %v", target, err)
+		}
+	}
+	// Group the merged results by registrable domain, then by the host they were actually found on.
+	apexes := make(map[string]string)
+	for _, r := range ordered {
+		if _, ok := apexes[r.Host]; !ok {
+			apexes[r.Host], _ = psl.RegistrableDomain(r.Host)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if apexes[ordered[i].Host] != apexes[ordered[j].Host] {
+			return apexes[ordered[i].Host] < apexes[ordered[j].Host]
+		}
+		return ordered[i].Host < ordered[j].Host
+	})
+	if err := w.WriteDomain(target, ordered); err != nil {
+		logrus.Errorf("Failed to write results for %s: %v", target, err)
+	}
+	if outOfScope > 0 {
+		logrus.Debugf("Dropped %d out-of-scope URLs for %s", outOfScope, target)
+	}
+	if opts.SmartDedupe {
+		logrus.Infof("Finished domain %s: %d unique URL patterns (%d similar URLs collapsed)", target, len(seen), len(absorbed))
+	} else {
+		logrus.Infof("Finished domain %s: %d unique URLs", target, len(seen))
+	}
+}
+
+// hostOf returns the normalized host name of rawURL, or an empty string if it cannot be parsed.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	host, err := psl.Normalize(u.Hostname())
+	if err != nil {
+		return ""
+	}
+	return host
+}
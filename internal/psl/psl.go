@@ -0,0 +1,208 @@
+// Package psl implements Public Suffix List lookups (https://publicsuffix.org/) and domain
+// normalization. An embedded copy of the list is used unless a newer one is loaded with UseFile.
+package psl
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+//go:embed public_suffix_list.dat
+var embeddedList string
+
+// List is a parsed Public Suffix List.
+type List struct {
+	rules      map[string]struct{} // Normal rules, e.g. "co.uk".
+	wildcards  map[string]struct{} // Parents of wildcard rules, e.g. "ck" for "*.ck".
+	exceptions map[string]struct{} // Exception rules without the "!", e.g. "www.ck".
+}
+
+// Parse reads a list in the publicsuffix.org format. Comments and blank lines are ignored, only
+// the first whitespace-separated field of each line is used, and internationalized rules are
+// stored in punycode so that they match normalized domains.
+func Parse(r io.Reader) (*List, error) {
+	l := &List{
+		rules:      make(map[string]struct{}),
+		wildcards:  make(map[string]struct{}),
+		exceptions: make(map[string]struct{}),
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		rule, err := ToASCII(strings.Fields(line)[0])
+		if err != nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(rule, "!"):
+			l.exceptions[rule[1:]] = struct{}{}
+		case strings.HasPrefix(rule, "*."):
+			l.wildcards[rule[2:]] = struct{}{}
+		default:
+			l.rules[rule] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(l.rules) == 0 && len(l.wildcards) == 0 {
+		return nil, fmt.Errorf("public suffix list contains no rules")
+	}
+	return l, nil
+}
+
+var (
+	defaultMu   sync.RWMutex
+	defaultList *List
+)
+
+// Default returns the list used by the package-level helpers: the embedded copy, unless it was
+// replaced with UseFile.
+func Default() *List {
+	defaultMu.RLock()
+	l := defaultList
+	defaultMu.RUnlock()
+	if l != nil {
+		return l
+	}
+
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultList == nil {
+		parsed, err := Parse(strings.NewReader(embeddedList))
+		if err != nil {
+			panic("psl: invalid embedded list: " + err.Error())
+		}
+		defaultList = parsed
+	}
+	return defaultList
+}
+
+// UseFile replaces the embedded list with a newer copy read from a local file, for example one
+// downloaded from https://publicsuffix.org/list/public_suffix_list.dat.
+func UseFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	l, err := Parse(f)
+	if err != nil {
+		return fmt.Errorf("error parsing public suffix list %s: %w", path, err)
+	}
+	defaultMu.Lock()
+	defaultList = l
+	defaultMu.Unlock()
+	return nil
+}
+
+// Normalize returns the canonical form of a domain or host: surrounding whitespace, any scheme,
+// path and port are removed, it is lower-cased without a trailing dot, and internationalized
+// labels are converted to punycode.
+func Normalize(domain string) (string, error) {
+	domain = strings.TrimSpace(domain)
+	if i := strings.Index(domain, "://"); i >= 0 {
+		domain = domain[i+3:]
+	}
+	if i := strings.IndexAny(domain, "/?#"); i >= 0 {
+		domain = domain[:i]
+	}
+	if i := strings.LastIndex(domain, "@"); i >= 0 {
+		domain = domain[i+1:]
+	}
+	if strings.HasPrefix(domain, "[") {
+		// IPv6 literal, optionally followed by a port.
+		if end := strings.Index(domain, "]"); end > 0 {
+			return strings.ToLower(domain[1:end]), nil
+		}
+	} else if i := strings.LastIndex(domain, ":"); i >= 0 && strings.Count(domain, ":") == 1 {
+		domain = domain[:i]
+	}
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" {
+		return "", fmt.Errorf("empty domain")
+	}
+	ascii, err := ToASCII(domain)
+	if err != nil {
+		return "", err
+	}
+	for _, label := range strings.Split(ascii, ".") {
+		if label == "" {
+			return "", fmt.Errorf("invalid domain %q: empty label", domain)
+		}
+	}
+	return ascii, nil
+}
+
+// IsSubdomain reports whether host is strictly below domain, comparing whole labels
+// ("api.example.com" is below "example.com", "badexample.com" is not).
+func IsSubdomain(host, domain string) bool {
+	h, err := Normalize(host)
+	if err != nil {
+		return false
+	}
+	d, err := Normalize(domain)
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(h, "."+d)
+}
+
+// PublicSuffix returns the public suffix of domain, e.g. "co.uk" for "www.example.co.uk".
+// If no rule matches, the last label is the public suffix (the implicit "*" rule).
+// The domain is expected to be normalized (see Normalize).
+func (l *List) PublicSuffix(domain string) string {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	labels := strings.Split(domain, ".")
+	for i := range labels {
+		candidate := strings.Join(labels[i:], ".")
+		if _, ok := l.exceptions[candidate]; ok {
+			return strings.Join(labels[i+1:], ".")
+		}
+		if _, ok := l.rules[candidate]; ok {
+			return candidate
+		}
+		if i+1 < len(labels) {
+			if _, ok := l.wildcards[strings.Join(labels[i+1:], ".")]; ok {
+				return candidate
+			}
+		}
+	}
+	return labels[len(labels)-1]
+}
+
+// RegistrableDomain returns the public suffix of domain plus one label, e.g. "example.co.uk"
+// for "www.example.co.uk". It returns an error if domain is itself a public suffix or an IP address.
+func (l *List) RegistrableDomain(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if net.ParseIP(domain) != nil {
+		return "", fmt.Errorf("%s is an IP address", domain)
+	}
+	suffix := l.PublicSuffix(domain)
+	if domain == suffix {
+		return "", fmt.Errorf("%s is a public suffix", domain)
+	}
+	rest := strings.TrimSuffix(domain, "."+suffix)
+	if i := strings.LastIndex(rest, "."); i >= 0 {
+		rest = rest[i+1:]
+	}
+	return rest + "." + suffix, nil
+}
+
+// RegistrableDomain normalizes domain and looks it up in the default list.
+func RegistrableDomain(domain string) (string, error) {
+	d, err := Normalize(domain)
+	if err != nil {
+		return "", err
+	}
+	return Default().RegistrableDomain(d)
+}
@@ -0,0 +1,33 @@
+package psl
+
+import "testing"
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := []struct {
+		domain, want string
+		wantErr      bool
+	}{
+		{domain: "example.com", want: "example.com"},
+		{domain: "www.example.co.uk", want: "example.co.uk"},
+		{domain: "https://API.Example.com:8443/path", want: "example.com"},
+		{domain: "www.bücher.de", want: "xn--bcher-kva.de"},
+		{domain: "co.uk", wantErr: true},
+		{domain: "192.168.0.1", wantErr: true},
+		{domain: "http://10.0.0.1:8080/", wantErr: true},
+		{domain: "[2001:db8::1]:443", wantErr: true},
+	}
+	for _, tc := range tests {
+		got, err := RegistrableDomain(tc.domain)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("RegistrableDomain(%q) = %q, want an error", tc.domain, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("RegistrableDomain(%q): %v", tc.domain, err)
+		} else if got != tc.want {
+			t.Errorf("RegistrableDomain(%q) = %q, want %q", tc.domain, got, tc.want)
+		}
+	}
+}
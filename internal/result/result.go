@@ -0,0 +1,131 @@
+package result
+
+import (
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Result is a URL harvested from one or more sources together with the capture metadata they reported.
+type Result struct {
+	URL        string     `json:"url"`
+	Host       string     `json:"host,omitempty"`        // Host the URL was found on.
+	Sources    []string   `json:"sources"`               // Names of the sources that returned the URL.
+	FirstSeen  *time.Time `json:"first_seen,omitempty"`  // Earliest capture reported by any source.
+	LastSeen   *time.Time `json:"last_seen,omitempty"`   // Latest capture reported by any source.
+	Status     int        `json:"status,omitempty"`      // HTTP status of the latest known capture.
+	MIME       string     `json:"mime,omitempty"`        // MIME type of the latest known capture.
+	Absorbed   int        `json:"absorbed,omitempty"`    // Distinct URLs collapsed into this one by pattern deduplication.
+	BodyParams []string   `json:"body_params,omitempty"` // Parameters only sent in a request body, added to the query (proxy imports).
+}
+
+// SetSeen records a capture time, widening the FirstSeen/LastSeen range as needed.
+// A zero time is ignored.
+func (r *Result) SetSeen(t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	t = t.UTC()
+	if r.FirstSeen == nil || t.Before(*r.FirstSeen) {
+		first := t
+		r.FirstSeen = &first
+	}
+	if r.LastSeen == nil || t.After(*r.LastSeen) {
+		last := t
+		r.LastSeen = &last
+	}
+}
+
+// Merge folds the metadata of other into r. Sources and body parameters are unioned, the seen range
+// is widened, and status and MIME type are taken from whichever record was captured most recently.
+func (r *Result) Merge(other Result) {
+	for _, s := range other.Sources {
+		r.addSource(s)
+	}
+	for _, name := range other.BodyParams {
+		r.AddBodyParam(name)
+	}
+
+	otherIsNewer := other.LastSeen != nil && (r.LastSeen == nil || other.LastSeen.After(*r.LastSeen))
+	if other.Status != 0 && (r.Status == 0 || otherIsNewer) {
+		r.Status = other.Status
+	}
+	if other.MIME != "" && (r.MIME == "" || otherIsNewer) {
+		r.MIME = other.MIME
+	}
+
+	if other.FirstSeen != nil {
+		r.SetSeen(*other.FirstSeen)
+	}
+	if other.LastSeen != nil {
+		r.SetSeen(*other.LastSeen)
+	}
+}
+
+// addSource inserts name into the sorted Sources list if it is not already present.
+func (r *Result) addSource(name string) {
+	r.Sources = insertSorted(r.Sources, name)
+}
+
+// AddBodyParam records that the parameter name was only sent in a request body.
+func (r *Result) AddBodyParam(name string) {
+	r.BodyParams = insertSorted(r.BodyParams, name)
+}
+
+// insertSorted inserts s into the sorted list if it is not already present.
+func insertSorted(list []string, s string) []string {
+	i := sort.SearchStrings(list, s)
+	if i < len(list) && list[i] == s {
+		return list
+	}
+	list = append(list, "")
+	copy(list[i+1:], list[i:])
+	list[i] = s
+	return list
+}
+
+// ParseTimestamp parses the capture timestamps used by the providers: 14-digit CDX timestamps
+// (20060102150405, possibly truncated), RFC 3339, and "2006-01-02 15:04:05" / "2006-01-02T15:04:05".
+// It returns the zero time if the value cannot be parsed.
+func ParseTimestamp(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	if _, err := strconv.Atoi(value); err == nil && len(value) >= 4 && len(value) <= 14 {
+		layout := "20060102150405"[:len(value)]
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// ParseStatus converts a status code reported as a string (for example "200" or "-") to an int.
+// It returns 0 if the value is not a valid HTTP status.
+func ParseStatus(value string) int {
+	code, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || code < 100 || code > 999 {
+		return 0
+	}
+	return code
+}
+
+// MediaType returns the lower-cased media type of a Content-Type value, without its parameters:
+// "text/html" for "text/html; charset=UTF-8". Malformed parameters are tolerated.
+func MediaType(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
@@ -0,0 +1,190 @@
+package scope
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/grumpzsux/goParams/internal/config"
+	"github.com/grumpzsux/goParams/internal/psl"
+)
+
+// Scope decides whether a harvested URL belongs to the target being processed.
+// Host rules accept exact hosts ("example.com"), wildcards ("*.example.com", which matches
+// subdomains but not the apex) and CIDR ranges ("10.0.0.0/8"). Path rules are prefixes,
+// and regular expressions are matched against the full URL.
+type Scope struct {
+	subdomains   bool
+	include      []hostRule
+	exclude      []hostRule
+	includePaths []string
+	excludePaths []string
+	excludeRegex []*regexp.Regexp
+}
+
+// hostRule is a single compiled host pattern. Host names are kept in normalized form.
+type hostRule struct {
+	exact    string
+	wildcard string // Parent domain of a "*." pattern, e.g. "example.com".
+	network  *net.IPNet
+}
+
+// matches reports whether a normalized host matches the rule.
+func (r hostRule) matches(host string) bool {
+	switch {
+	case r.network != nil:
+		ip := net.ParseIP(host)
+		return ip != nil && r.network.Contains(ip)
+	case r.wildcard != "":
+		return psl.IsSubdomain(host, r.wildcard)
+	default:
+		return host == r.exact
+	}
+}
+
+// parseHostRule compiles a host pattern.
+func parseHostRule(pattern string) (hostRule, error) {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return hostRule{}, fmt.Errorf("empty host pattern")
+	}
+	if strings.Contains(pattern, "/") {
+		_, network, err := net.ParseCIDR(pattern)
+		if err != nil {
+			return hostRule{}, fmt.Errorf("invalid CIDR %q: %w", pattern, err)
+		}
+		return hostRule{network: network}, nil
+	}
+	wildcard := strings.HasPrefix(pattern, "*.")
+	if wildcard {
+		pattern = pattern[2:]
+	}
+	if strings.Contains(pattern, "*") {
+		return hostRule{}, fmt.Errorf("invalid host pattern %q: only a leading \"*.\" wildcard is supported", pattern)
+	}
+	host, err := psl.Normalize(pattern)
+	if err != nil {
+		return hostRule{}, fmt.Errorf("invalid host pattern %q: %w", pattern, err)
+	}
+	if wildcard {
+		return hostRule{wildcard: host}, nil
+	}
+	return hostRule{exact: host}, nil
+}
+
+// New compiles scope rules. With includeSubdomains, the implicit target rule also covers every
+// subdomain of the target.
+func New(rules config.ScopeRules, includeSubdomains bool) (*Scope, error) {
+	s := &Scope{subdomains: includeSubdomains}
+	for _, pattern := range rules.Include {
+		r, err := parseHostRule(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("scope include: %w", err)
+		}
+		s.include = append(s.include, r)
+	}
+	for _, pattern := range rules.Exclude {
+		r, err := parseHostRule(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("scope exclude: %w", err)
+		}
+		s.exclude = append(s.exclude, r)
+	}
+	s.includePaths = append(s.includePaths, rules.IncludePaths...)
+	s.excludePaths = append(s.excludePaths, rules.ExcludePaths...)
+	for _, expr := range rules.ExcludeRegex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("scope exclude_regex %q: %w", expr, err)
+		}
+		s.excludeRegex = append(s.excludeRegex, re)
+	}
+	return s, nil
+}
+
+// LoadFile reads scope rules from a YAML file with the same shape as the "scope" configuration key.
+func LoadFile(path string) (config.ScopeRules, error) {
+	var rules config.ScopeRules
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rules, err
+	}
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return rules, fmt.Errorf("error parsing scope file %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// targetHosts returns the implicit host rules used when no include rules are configured:
+// the target itself and its "www." variant, which archives treat as the same host, plus every
+// subdomain when subdomains are included.
+func (s *Scope) targetHosts(target string) []hostRule {
+	if normalized, err := psl.Normalize(target); err == nil {
+		target = normalized
+	}
+	if s.subdomains {
+		return []hostRule{{exact: target}, {wildcard: target}}
+	}
+	return []hostRule{{exact: target}, {exact: "www." + target}}
+}
+
+// InScope reports whether rawURL is in scope for target.
+// Explicit include rules replace the implicit rule that only the target host is in scope.
+func (s *Scope) InScope(rawURL, target string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host, err := psl.Normalize(u.Hostname())
+	if err != nil {
+		return false
+	}
+
+	include := s.include
+	if len(include) == 0 {
+		include = s.targetHosts(target)
+	}
+	if !matchesAny(include, host) || matchesAny(s.exclude, host) {
+		return false
+	}
+
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	if len(s.includePaths) > 0 && !hasAnyPrefix(p, s.includePaths) {
+		return false
+	}
+	if hasAnyPrefix(p, s.excludePaths) {
+		return false
+	}
+	for _, re := range s.excludeRegex {
+		if re.MatchString(rawURL) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAny(rules []hostRule, host string) bool {
+	for _, r := range rules {
+		if r.matches(host) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(p string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package scope
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/grumpzsux/goParams/internal/config"
+)
+
+func TestInScope(t *testing.T) {
+	tests := []struct {
+		name       string
+		rules      config.ScopeRules
+		subdomains bool
+		url        string
+		want       bool
+	}{
+		// Without include rules the target and its www. variant are in scope.
+		{"target", config.ScopeRules{}, false, "https://example.com/a?x=1", true},
+		{"www variant", config.ScopeRules{}, false, "https://www.example.com/a?x=1", true},
+		{"other subdomain", config.ScopeRules{}, false, "https://api.example.com/a?x=1", false},
+		{"other domain", config.ScopeRules{}, false, "https://example.org/a?x=1", false},
+		{"lookalike suffix", config.ScopeRules{}, false, "https://badexample.com/a?x=1", false},
+		{"upper-case host", config.ScopeRules{}, false, "https://WWW.Example.COM/a?x=1", true},
+		{"unparsable", config.ScopeRules{}, false, "https://exa mple.com/%zz", false},
+		{"subdomains included", config.ScopeRules{}, true, "https://api.example.com/a?x=1", true},
+		{"deep subdomain included", config.ScopeRules{}, true, "https://a.b.example.com/a?x=1", true},
+		{"apex with subdomains", config.ScopeRules{}, true, "https://example.com/a?x=1", true},
+
+		// Ports do not affect host matching.
+		{"port on target", config.ScopeRules{}, false, "https://example.com:8443/a?x=1", true},
+		{"port on include", config.ScopeRules{Include: []string{"api.example.com"}}, false, "http://api.example.com:8080/?x=1", true},
+
+		// Include rules replace the target.
+		{"exact include", config.ScopeRules{Include: []string{"api.example.com"}}, false, "https://api.example.com/?x=1", true},
+		{"exact include drops target", config.ScopeRules{Include: []string{"api.example.com"}}, false, "https://example.com/?x=1", false},
+		{"wildcard include subdomain", config.ScopeRules{Include: []string{"*.example.com"}}, false, "https://a.b.example.com/?x=1", true},
+		{"wildcard include skips apex", config.ScopeRules{Include: []string{"*.example.com"}}, false, "https://example.com/?x=1", false},
+		{"wildcard include other domain", config.ScopeRules{Include: []string{"*.example.com"}}, false, "https://example.org/?x=1", false},
+
+		// Exclude rules win over include rules.
+		{"exact exclude", config.ScopeRules{Include: []string{"*.example.com"}, Exclude: []string{"cdn.example.com"}}, false, "https://cdn.example.com/?x=1", false},
+		{"exact exclude keeps siblings", config.ScopeRules{Include: []string{"*.example.com"}, Exclude: []string{"cdn.example.com"}}, false, "https://api.example.com/?x=1", true},
+		{"wildcard exclude", config.ScopeRules{Exclude: []string{"*.dev.example.com"}}, true, "https://a.dev.example.com/?x=1", false},
+		{"wildcard exclude keeps parent", config.ScopeRules{Exclude: []string{"*.dev.example.com"}}, true, "https://dev.example.com/?x=1", true},
+
+		// CIDR ranges match IP hosts only.
+		{"IPv4 in range", config.ScopeRules{Include: []string{"10.0.0.0/8"}}, false, "http://10.1.2.3/?x=1", true},
+		{"IPv4 with port in range", config.ScopeRules{Include: []string{"10.0.0.0/8"}}, false, "http://10.1.2.3:8080/?x=1", true},
+		{"IPv4 out of range", config.ScopeRules{Include: []string{"10.0.0.0/8"}}, false, "http://192.168.1.1/?x=1", false},
+		{"hostname against range", config.ScopeRules{Include: []string{"10.0.0.0/8"}}, false, "http://example.com/?x=1", false},
+		{"IPv6 in range", config.ScopeRules{Include: []string{"2001:db8::/32"}}, false, "http://[2001:db8::1]:8080/?x=1", true},
+		{"IPv6 out of range", config.ScopeRules{Include: []string{"2001:db8::/32"}}, false, "http://[2001:db9::1]/?x=1", false},
+		{"excluded range", config.ScopeRules{Include: []string{"10.0.0.0/8"}, Exclude: []string{"10.0.0.0/24"}}, false, "http://10.0.0.7/?x=1", false},
+		{"exact IP include", config.ScopeRules{Include: []string{"192.0.2.1"}}, false, "http://192.0.2.1/?x=1", true},
+
+		// Path and regex rules.
+		{"include path", config.ScopeRules{IncludePaths: []string{"/api/"}}, false, "https://example.com/api/users?id=1", true},
+		{"outside include path", config.ScopeRules{IncludePaths: []string{"/api/"}}, false, "https://example.com/static/x?v=1", false},
+		{"empty path", config.ScopeRules{IncludePaths: []string{"/"}}, false, "https://example.com?x=1", true},
+		{"exclude path", config.ScopeRules{ExcludePaths: []string{"/static/"}}, false, "https://example.com/static/app.js?v=1", false},
+		{"exclude regex", config.ScopeRules{ExcludeRegex: []string{`[?&]utm_`}}, false, "https://example.com/?utm_source=x", false},
+		{"regex not matched", config.ScopeRules{ExcludeRegex: []string{`[?&]utm_`}}, false, "https://example.com/?id=1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(tt.rules, tt.subdomains)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if got := s.InScope(tt.url, "example.com"); got != tt.want {
+				t.Errorf("InScope(%q) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules config.ScopeRules
+	}{
+		{"empty host", config.ScopeRules{Include: []string{" "}}},
+		{"inner wildcard", config.ScopeRules{Include: []string{"api.*.example.com"}}},
+		{"bad CIDR", config.ScopeRules{Exclude: []string{"10.0.0.0/33"}}},
+		{"bad regex", config.ScopeRules{ExcludeRegex: []string{"("}}},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.rules, false); err == nil {
+			t.Errorf("%s: New succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scope.yaml")
+	content := "include:\n  - \"*.example.com\"\nexclude:\n  - 10.0.0.0/8\nexclude_paths:\n  - /static/\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := config.ScopeRules{
+		Include:      []string{"*.example.com"},
+		Exclude:      []string{"10.0.0.0/8"},
+		ExcludePaths: []string{"/static/"},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("LoadFile = %+v, want %+v", rules, want)
+	}
+}
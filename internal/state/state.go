@@ -0,0 +1,165 @@
+// Package state keeps, per target, the cleaned URLs seen by earlier runs so that a run can report
+// only the URLs that are new since then.
+package state
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/grumpzsux/goParams/internal/result"
+	"github.com/grumpzsux/goParams/internal/utils"
+)
+
+// DefaultDir returns the default state directory, $XDG_STATE_HOME/goParams
+// (~/.local/state/goParams if unset).
+func DefaultDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "goParams"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "goParams"), nil
+}
+
+// Store reads and writes the per-target state files in a directory. Each file lists the cleaned URLs
+// seen for one target, one per line, sorted.
+type Store struct {
+	dir string
+}
+
+// Open returns the store in dir, creating the directory if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+// path returns the state file of target. Targets are normalized host names, which are safe file names.
+func (s *Store) path(target string) string {
+	return filepath.Join(s.dir, strings.ReplaceAll(target, string(filepath.Separator), "_")+".txt")
+}
+
+// Seen returns the URLs recorded for target; it is empty if the target has no state yet.
+func (s *Store) Seen(target string) (map[string]struct{}, error) {
+	seen := make(map[string]struct{})
+	f, err := os.Open(s.path(target))
+	if errors.Is(err, os.ErrNotExist) {
+		return seen, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			seen[line] = struct{}{}
+		}
+	}
+	return seen, scanner.Err()
+}
+
+// Save replaces the state of target with urls.
+func (s *Store) Save(target string, urls map[string]struct{}) error {
+	list := make([]string, 0, len(urls))
+	for u := range urls {
+		list = append(list, u)
+	}
+	sort.Strings(list)
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	for _, u := range list {
+		w.WriteString(u + "\n")
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(target))
+}
+
+// newOnlyWriter passes on only the results whose URL is not in the target's state, and adds every
+// result to the state once the target has finished.
+type newOnlyWriter struct {
+	next  utils.ResultWriter
+	store *Store
+
+	mu   sync.Mutex
+	seen map[string]map[string]struct{} // Previous state by target, loaded on first use.
+}
+
+// NewOnly wraps w so that it only receives URLs not seen by earlier runs, as recorded in store.
+// When a target finishes, its state is updated with all of the URLs found for it.
+func NewOnly(w utils.ResultWriter, store *Store) utils.ResultWriter {
+	return &newOnlyWriter{next: w, store: store, seen: make(map[string]map[string]struct{})}
+}
+
+// previous returns the state of target as it was before this run.
+func (n *newOnlyWriter) previous(target string) (map[string]struct{}, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if seen, ok := n.seen[target]; ok {
+		return seen, nil
+	}
+	seen, err := n.store.Seen(target)
+	if err != nil {
+		return nil, err
+	}
+	n.seen[target] = seen
+	return seen, nil
+}
+
+func (n *newOnlyWriter) Write(domain string, r *result.Result) error {
+	seen, err := n.previous(domain)
+	if err != nil {
+		return err
+	}
+	if _, old := seen[r.URL]; old {
+		return nil
+	}
+	return n.next.Write(domain, r)
+}
+
+func (n *newOnlyWriter) WriteDomain(domain string, results []*result.Result) error {
+	seen, err := n.previous(domain)
+	if err != nil {
+		return err
+	}
+	var added []*result.Result
+	updated := make(map[string]struct{}, len(seen)+len(results))
+	for u := range seen {
+		updated[u] = struct{}{}
+	}
+	for _, r := range results {
+		if _, old := seen[r.URL]; !old {
+			added = append(added, r)
+		}
+		updated[r.URL] = struct{}{}
+	}
+	if err := n.next.WriteDomain(domain, added); err != nil {
+		return err
+	}
+	return n.store.Save(domain, updated)
+}
+
+func (n *newOnlyWriter) Close() error {
+	return n.next.Close()
+}
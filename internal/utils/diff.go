@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// LoadURLSet reads the URLs of a goParams output file. It accepts the JSON Lines records written
+// with -f json, where a URL whose record was written again counts once, the legacy JSON object
+// mapping each domain to its URLs (see OutputJSON and WriteResultsToFile), and plain output with one
+// URL per line.
+func LoadURLSet(filename string) (map[string]struct{}, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	urls := make(map[string]struct{})
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		// A stream of objects: JSON Lines records carry a "url", the legacy object only domain keys.
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		for dec.More() {
+			var obj map[string]json.RawMessage
+			if err := dec.Decode(&obj); err != nil {
+				return nil, fmt.Errorf("%s: %w", filename, err)
+			}
+			if raw, ok := obj["url"]; ok {
+				var u string
+				if err := json.Unmarshal(raw, &u); err == nil && u != "" {
+					urls[u] = struct{}{}
+				}
+				continue
+			}
+			for _, raw := range obj {
+				var list []string
+				if err := json.Unmarshal(raw, &list); err != nil {
+					return nil, fmt.Errorf("%s: %w", filename, err)
+				}
+				for _, u := range list {
+					urls[u] = struct{}{}
+				}
+			}
+		}
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			// Skip the "Host: ..." headers of grouped plain output, and the "Domain: ..." headers
+			// of legacy plain output.
+			if line == "" || strings.HasPrefix(line, "Host: ") || strings.HasPrefix(line, "Domain: ") {
+				continue
+			}
+			// Drop the absorbed count that smart-dedupe adds after a tab.
+			if i := strings.IndexByte(line, '\t'); i >= 0 {
+				line = line[:i]
+			}
+			urls[line] = struct{}{}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+	return urls, nil
+}
+
+// URLDiff lists what changed between two sets of URLs.
+type URLDiff struct {
+	AddedURLs     []string `json:"added_urls"`
+	RemovedURLs   []string `json:"removed_urls"`
+	AddedParams   []string `json:"added_params"`   // Parameter names found only in the new set.
+	RemovedParams []string `json:"removed_params"` // Parameter names found only in the old set.
+}
+
+// DiffURLs compares two sets of URLs, reporting added and removed URLs and parameter names.
+// All lists are sorted.
+func DiffURLs(oldURLs, newURLs map[string]struct{}) URLDiff {
+	return URLDiff{
+		AddedURLs:     setDifference(newURLs, oldURLs),
+		RemovedURLs:   setDifference(oldURLs, newURLs),
+		AddedParams:   setDifference(paramNames(newURLs), paramNames(oldURLs)),
+		RemovedParams: setDifference(paramNames(oldURLs), paramNames(newURLs)),
+	}
+}
+
+// paramNames returns the query parameter names used by urls.
+func paramNames(urls map[string]struct{}) map[string]struct{} {
+	names := make(map[string]struct{})
+	for raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		for name := range u.Query() {
+			if name != "" {
+				names[name] = struct{}{}
+			}
+		}
+	}
+	return names
+}
+
+// setDifference returns the sorted elements of a that are not in b.
+func setDifference(a, b map[string]struct{}) []string {
+	out := []string{}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// WriteDiff writes d in the given format. Plain output prefixes added entries with "+" and removed
+// entries with "-", URLs first and then parameter names marked "param:".
+func WriteDiff(w io.Writer, d URLDiff, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(d); err != nil {
+			return fmt.Errorf("error formatting JSON output: %w", err)
+		}
+		return nil
+	case "", "plain":
+		bw := bufio.NewWriter(w)
+		for _, u := range d.AddedURLs {
+			fmt.Fprintf(bw, "+ %s\n", u)
+		}
+		for _, u := range d.RemovedURLs {
+			fmt.Fprintf(bw, "- %s\n", u)
+		}
+		for _, p := range d.AddedParams {
+			fmt.Fprintf(bw, "+ param:%s\n", p)
+		}
+		for _, p := range d.RemovedParams {
+			fmt.Fprintf(bw, "- param:%s\n", p)
+		}
+		return bw.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
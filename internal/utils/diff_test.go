@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func urlSet(urls ...string) map[string]struct{} {
+	set := make(map[string]struct{}, len(urls))
+	for _, u := range urls {
+		set[u] = struct{}{}
+	}
+	return set
+}
+
+// TestLoadURLSetRewrittenJSONLines loads JSON output in which a record was written again with merged
+// metadata when its domain finished, and checks that the URL counts once.
+func TestLoadURLSetRewrittenJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewResultWriter(&buf, "json", OutputOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &result.Result{URL: "https://example.com/a?x=1", Sources: []string{"wayback"}}
+	b := &result.Result{URL: "https://example.com/b?y=1", Sources: []string{"wayback"}}
+	for _, r := range []*result.Result{a, b} {
+		if err := w.Write("example.com", r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.Merge(result.Result{URL: a.URL, Sources: []string{"commoncrawl"}})
+	if err := w.WriteDomain("example.com", []*result.Result{a, b}); err != nil {
+		t.Fatal(err)
+	}
+	if lines := bytes.Count(buf.Bytes(), []byte("\n")); lines != 3 {
+		t.Fatalf("wrote %d lines, want 3 (one record written again):\n%s", lines, buf.String())
+	}
+
+	got, err := LoadURLSet(writeFile(t, "out.json", buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := urlSet(a.URL, b.URL); !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadURLSet = %v, want %v", got, want)
+	}
+}
+
+func TestLoadURLSetFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]struct{}
+	}{
+		{
+			name:    "legacy JSON object",
+			content: "{\n  \"example.com\": [\"https://example.com/?a=1\", \"https://example.com/?b=2\"]\n}\n",
+			want:    urlSet("https://example.com/?a=1", "https://example.com/?b=2"),
+		},
+		{
+			name:    "plain",
+			content: "https://example.com/?a=1\nhttps://example.com/?b=2\n",
+			want:    urlSet("https://example.com/?a=1", "https://example.com/?b=2"),
+		},
+		{
+			name:    "plain grouped with absorbed counts",
+			content: "Host: example.com\nhttps://example.com/?a=1\t3\n\nHost: www.example.com\nhttps://www.example.com/?b=2\t0\n",
+			want:    urlSet("https://example.com/?a=1", "https://www.example.com/?b=2"),
+		},
+		{
+			name:    "legacy plain",
+			content: "Domain: example.com\nhttps://example.com/?a=1\n\n",
+			want:    urlSet("https://example.com/?a=1"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadURLSet(writeFile(t, "out", tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadURLSet = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// ParamStat describes a query parameter name, how often it was seen and where.
+type ParamStat struct {
+	Name     string   `json:"name"`
+	Domain   string   `json:"domain,omitempty"`   // Set only when stats are collected per domain.
+	Count    int      `json:"count,omitempty"`    // Number of unique URLs carrying the parameter.
+	Examples []string `json:"examples,omitempty"` // Example endpoints (scheme, host and path) using the parameter.
+}
+
+// ParamCollector is a ResultWriter that tallies query parameter names instead of writing URLs.
+type ParamCollector struct {
+	mu          sync.Mutex
+	perDomain   bool
+	maxExamples int
+	stats       map[string]*ParamStat
+	examples    map[string]map[string]struct{}
+}
+
+// NewParamCollector returns a collector that groups names per domain (or globally) and keeps up to
+// maxExamples example endpoints for each name.
+func NewParamCollector(perDomain bool, maxExamples int) *ParamCollector {
+	return &ParamCollector{
+		perDomain:   perDomain,
+		maxExamples: maxExamples,
+		stats:       make(map[string]*ParamStat),
+		examples:    make(map[string]map[string]struct{}),
+	}
+}
+
+// Write records the query parameter names of a cleaned URL.
+func (c *ParamCollector) Write(domain string, r *result.Result) error {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return nil
+	}
+	endpoint := u.Scheme + "://" + u.Host + u.EscapedPath()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name := range u.Query() {
+		if name == "" {
+			continue
+		}
+		key := name
+		if c.perDomain {
+			key = domain + "\x00" + name
+		}
+		stat, ok := c.stats[key]
+		if !ok {
+			stat = &ParamStat{Name: name}
+			if c.perDomain {
+				stat.Domain = domain
+			}
+			c.stats[key] = stat
+			c.examples[key] = make(map[string]struct{})
+		}
+		stat.Count++
+		if len(stat.Examples) < c.maxExamples {
+			if _, dup := c.examples[key][endpoint]; !dup {
+				c.examples[key][endpoint] = struct{}{}
+				stat.Examples = append(stat.Examples, endpoint)
+			}
+		}
+	}
+	return nil
+}
+
+// WriteDomain is a no-op; names are tallied as URLs arrive.
+func (c *ParamCollector) WriteDomain(domain string, results []*result.Result) error {
+	return nil
+}
+
+// Close is a no-op; call Stats to retrieve the tally.
+func (c *ParamCollector) Close() error {
+	return nil
+}
+
+// Stats returns the collected names sorted by domain, then by descending count, then by name.
+func (c *ParamCollector) Stats() []ParamStat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := make([]ParamStat, 0, len(c.stats))
+	for _, stat := range c.stats {
+		copied := *stat
+		copied.Examples = append([]string(nil), stat.Examples...)
+		stats = append(stats, copied)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Domain != stats[j].Domain {
+			return stats[i].Domain < stats[j].Domain
+		}
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// WriteParams writes parameter stats in the given format ("plain" or "json").
+// Plain output is one name per line, optionally followed by a tab and its count, with any
+// examples on indented lines below; per-domain stats are preceded by a "Domain:" header.
+func WriteParams(w io.Writer, stats []ParamStat, format string, withCounts bool) error {
+	if !withCounts {
+		for i := range stats {
+			stats[i].Count = 0
+		}
+	}
+
+	if format == "json" {
+		if stats == nil {
+			stats = []ParamStat{}
+		}
+		b, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error formatting JSON output: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+
+	currentDomain := ""
+	for _, stat := range stats {
+		if stat.Domain != "" && stat.Domain != currentDomain {
+			if currentDomain != "" {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "Domain: %s\n", stat.Domain)
+			currentDomain = stat.Domain
+		}
+		line := stat.Name
+		if withCounts {
+			line = fmt.Sprintf("%s\t%d", stat.Name, stat.Count)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		for _, example := range stat.Examples {
+			fmt.Fprintf(w, "    %s\n", example)
+		}
+	}
+	return nil
+}
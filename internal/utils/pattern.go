@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	numericSegment = regexp.MustCompile(`^\d+$`)
+	uuidSegment    = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	hashSegment    = regexp.MustCompile(`^(?i)[0-9a-f]{16,}$`)
+	dateSegment    = regexp.MustCompile(`^\d{4}[-_.]?\d{2}[-_.]?\d{2}$`)
+	slugSegment    = regexp.MustCompile(`^(?i)[a-z0-9]+(?:[-_][a-z0-9]+){2,}$`)
+	hexIDPart      = regexp.MustCompile(`^(?i)[0-9a-f]*\d[0-9a-f]*$`)
+)
+
+// isSlug reports whether segment looks like a generated slug, such as "my-first-post-42" or
+// "how-to-reset-your-password". Short word lists like "get-user-info" are usually endpoint names, so a
+// slug needs at least four words or a word that is a numeric or hex ID.
+func isSlug(segment string) bool {
+	if !slugSegment.MatchString(segment) {
+		return false
+	}
+	parts := strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' })
+	if len(parts) >= 4 {
+		return true
+	}
+	for _, part := range parts {
+		if numericSegment.MatchString(part) || (len(part) >= 6 && hexIDPart.MatchString(part)) {
+			return true
+		}
+	}
+	return false
+}
+
+// segmentShape replaces a path segment that looks like an identifier with a placeholder describing its shape.
+// Segments that do not look like identifiers are returned unchanged.
+func segmentShape(segment string) string {
+	ext := path.Ext(segment)
+	base := strings.TrimSuffix(segment, ext)
+	// A trailing extension that is not a real extension (e.g. "1.5") is part of the segment.
+	if ext != "" && numericSegment.MatchString(strings.TrimPrefix(ext, ".")) {
+		base, ext = segment, ""
+	}
+
+	var shape string
+	switch {
+	case base == "":
+		return segment
+	case dateSegment.MatchString(base):
+		shape = "{date}"
+	case numericSegment.MatchString(base):
+		shape = "{int}"
+	case uuidSegment.MatchString(base):
+		shape = "{uuid}"
+	case hashSegment.MatchString(base):
+		shape = "{hash}"
+	case isSlug(base):
+		shape = "{slug}"
+	default:
+		return segment
+	}
+	return shape + ext
+}
+
+// PatternKey returns a key describing the shape of a URL: its host, its path with identifier-like
+// segments (numeric IDs, UUIDs, hashes, dates and slugs) replaced by placeholders, and the sorted set
+// of query parameter names. URLs that differ only in parameter values or identifier segments share a key.
+func PatternKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, segment := range segments {
+		segments[i] = segmentShape(segment)
+	}
+
+	names := make([]string, 0, len(u.Query()))
+	for name := range u.Query() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.ToLower(u.Host) + strings.Join(segments, "/") + "?" + strings.Join(names, "&")
+}
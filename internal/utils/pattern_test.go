@@ -0,0 +1,59 @@
+package utils
+
+import "testing"
+
+func TestSegmentShape(t *testing.T) {
+	tests := []struct {
+		segment string
+		want    string
+	}{
+		// Identifiers collapse to their shape.
+		{"12345", "{int}"},
+		{"12345.json", "{int}.json"},
+		{"2024-05-01", "{date}"},
+		{"20240501", "{date}"},
+		{"3f2504e0-4f89-11d3-9a0c-0305e82c3301", "{uuid}"},
+		{"d41d8cd98f00b204e9800998ecf8427e", "{hash}"},
+		{"my-first-post-42", "{slug}"},
+		{"summer-sale-2024", "{slug}"},
+		{"red-shoes-a1b2c3", "{slug}"},
+		{"how-to-reset-your-password", "{slug}"},
+		{"how_to_reset_your_password.html", "{slug}.html"},
+		// Endpoint names and short word lists are kept.
+		{"get-user-info", "get-user-info"},
+		{"reset_password_confirm", "reset_password_confirm"},
+		{"sign-in-form", "sign-in-form"},
+		{"oauth2-callback-url", "oauth2-callback-url"},
+		{"api-v2-users", "api-v2-users"},
+		{"user-profile", "user-profile"},
+		{"login", "login"},
+		{"v1.5", "v1.5"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := segmentShape(tt.segment); got != tt.want {
+			t.Errorf("segmentShape(%q) = %q, want %q", tt.segment, got, tt.want)
+		}
+	}
+}
+
+func TestPatternKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"https://example.com/item/123?id=1", "https://example.com/item/456?id=2", true},
+		{"https://example.com/blog/my-first-post-1?ref=a", "https://example.com/blog/another-post-2?ref=b", true},
+		{"https://example.com/a?x=1&y=2", "https://EXAMPLE.com/a?y=3&x=4", true},
+		{"https://example.com/api/get-user-info?id=1", "https://example.com/api/get-user-list?id=1", false},
+		{"https://example.com/reset_password_confirm?t=1", "https://example.com/reset_password_request?t=1", false},
+		{"https://example.com/item/123?id=1", "https://example.com/item/123?name=1", false},
+		{"https://example.com/item/123?id=1", "https://www.example.com/item/123?id=1", false},
+	}
+	for _, tt := range tests {
+		ka, kb := PatternKey(tt.a), PatternKey(tt.b)
+		if (ka == kb) != tt.same {
+			t.Errorf("PatternKey(%q) = %q, PatternKey(%q) = %q; same = %v, want %v", tt.a, ka, tt.b, kb, ka == kb, tt.same)
+		}
+	}
+}
@@ -45,6 +45,31 @@ func CleanURL(rawURL string) string {
 	return u.String()
 }
 
+// CleanURLString applies the CleanURLs steps to a single URL.
+// It returns false if the URL should be skipped because of its file extension.
+func CleanURLString(rawURL string, extensions []string, placeholder string) (string, bool) {
+	// Clean the URL.
+	cleanedURL := CleanURL(rawURL)
+	// Skip URL if it has one of the hardcoded unwanted extensions.
+	if HasExtension(cleanedURL, extensions) {
+		return "", false
+	}
+
+	u, err := url.Parse(cleanedURL)
+	if err != nil {
+		// If URL parsing fails, keep the original cleaned URL.
+		return cleanedURL, true
+	}
+
+	// Replace each query parameter's value with the placeholder.
+	q := u.Query()
+	for key := range q {
+		q.Set(key, placeholder)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), true
+}
+
 // CleanURLs processes a list of URLs:
 //  1. It first cleans each URL (removing redundant port info).
 //  2. Then it skips any URLs whose path has one of the excluded file extensions.
@@ -54,28 +79,9 @@ func CleanURLs(urls []string, extensions []string, placeholder string) []string
 	cleanedSet := make(map[string]struct{})
 
 	for _, rawURL := range urls {
-		// Clean the URL.
-		cleanedURL := CleanURL(rawURL)
-		// Skip URL if it has one of the hardcoded unwanted extensions.
-		if HasExtension(cleanedURL, extensions) {
-			continue
-		}
-
-		u, err := url.Parse(cleanedURL)
-		if err != nil {
-			// If URL parsing fails, keep the original cleaned URL.
-			cleanedSet[cleanedURL] = struct{}{}
-			continue
+		if cleaned, ok := CleanURLString(rawURL, extensions, placeholder); ok {
+			cleanedSet[cleaned] = struct{}{}
 		}
-
-		// Replace each query parameter's value with the placeholder.
-		q := u.Query()
-		for key := range q {
-			q.Set(key, placeholder)
-		}
-		u.RawQuery = q.Encode()
-
-		cleanedSet[u.String()] = struct{}{}
 	}
 
 	// Convert the set to a slice.
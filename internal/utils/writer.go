@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/grumpzsux/goParams/internal/result"
+)
+
+// ResultWriter receives cleaned results as they are produced.
+// Implementations are safe for concurrent use.
+type ResultWriter interface {
+	// Write is called the first time a cleaned URL is found for the given domain.
+	Write(domain string, r *result.Result) error
+	// WriteDomain is called once a domain has finished, with its results merged across all sources.
+	WriteDomain(domain string, results []*result.Result) error
+	// Close finishes the output (for example, terminating a JSON document).
+	// It does not close the underlying io.Writer.
+	Close() error
+}
+
+// OutputOptions controls the layout of plain output.
+type OutputOptions struct {
+	// GroupByHost writes each domain's URLs under a "Host: " header for every host they were found on.
+	GroupByHost bool
+	// Absorbed appends to each URL, after a tab, the number of similar URLs it absorbed in smart-dedupe mode.
+	Absorbed bool
+}
+
+// NewResultWriter returns a ResultWriter for the given format ("plain" or "json").
+// Both formats are streamed URL by URL, except plain output grouped by host or with absorbed
+// counts, which needs each domain's complete results and is written as each domain finishes. JSON output is written as JSON
+// Lines, with a record written again when its domain finishes if other sources added metadata to it.
+func NewResultWriter(w io.Writer, format string, opts OutputOptions) (ResultWriter, error) {
+	switch format {
+	case "", "plain":
+		return &plainWriter{w: w, opts: opts}, nil
+	case "json":
+		return &jsonWriter{w: w, written: make(map[*result.Result][]byte)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+// plainWriter writes one URL per line.
+type plainWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	opts OutputOptions
+}
+
+// streaming reports whether URLs are written as they are found rather than when their domain finishes.
+func (p *plainWriter) streaming() bool {
+	return !p.opts.GroupByHost && !p.opts.Absorbed
+}
+
+func (p *plainWriter) Write(domain string, r *result.Result) error {
+	if !p.streaming() {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := fmt.Fprintln(p.w, r.URL)
+	return err
+}
+
+// WriteDomain writes the domain's results, which arrive sorted by host, if they were not streamed.
+// When grouped, each host's URLs follow a "Host: " header and end with a blank line.
+func (p *plainWriter) WriteDomain(domain string, results []*result.Result) error {
+	if p.streaming() || len(results) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	for i, r := range results {
+		if p.opts.GroupByHost && (i == 0 || r.Host != results[i-1].Host) {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			fmt.Fprintf(&sb, "Host: %s\n", r.Host)
+		}
+		sb.WriteString(r.URL)
+		if p.opts.Absorbed {
+			fmt.Fprintf(&sb, "\t%d", r.Absorbed)
+		}
+		sb.WriteString("\n")
+	}
+	if p.opts.GroupByHost {
+		sb.WriteString("\n")
+	}
+	// The domain is written in one piece so that concurrent domains do not interleave.
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := io.WriteString(p.w, sb.String())
+	return err
+}
+
+func (p *plainWriter) Close() error {
+	return nil
+}
+
+// jsonRecord is a single entry in the JSON output.
+type jsonRecord struct {
+	Domain string `json:"domain"`
+	*result.Result
+}
+
+// jsonWriter writes one JSON record per line, as soon as a URL is first found. When the domain
+// finishes, the records whose metadata changed since (as other sources reported the same URL) are
+// written again; a later line for a URL supersedes the earlier ones.
+type jsonWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	written map[*result.Result][]byte // Last line written for each result of unfinished domains.
+}
+
+func (j *jsonWriter) Write(domain string, r *result.Result) error {
+	b, err := json.Marshal(jsonRecord{Domain: domain, Result: r})
+	if err != nil {
+		return fmt.Errorf("error formatting JSON output: %w", err)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.written[r] = b
+	_, err = fmt.Fprintf(j.w, "%s\n", b)
+	return err
+}
+
+func (j *jsonWriter) WriteDomain(domain string, results []*result.Result) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, r := range results {
+		b, err := json.Marshal(jsonRecord{Domain: domain, Result: r})
+		if err != nil {
+			return fmt.Errorf("error formatting JSON output: %w", err)
+		}
+		prev, ok := j.written[r]
+		delete(j.written, r)
+		if ok && bytes.Equal(prev, b) {
+			continue
+		}
+		if _, err := fmt.Fprintf(j.w, "%s\n", b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *jsonWriter) Close() error {
+	return nil
+}